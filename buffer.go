@@ -36,6 +36,16 @@ func (b *BytesBuffer) Write(vals []byte) {
 	b.pos += len(vals)
 }
 
+func (b *BytesBuffer) WriteString(val string) {
+	if b.pos+len(val) > len(b.buf) {
+		newBuf := make([]byte, max(2*len(b.buf), b.pos+len(val)))
+		copy(newBuf, b.buf[:b.pos])
+		b.buf = newBuf
+	}
+	copy(b.buf[b.pos:], val)
+	b.pos += len(val)
+}
+
 func (b *BytesBuffer) WriteByte(val byte) error {
 	if b.pos+1 > len(b.buf) {
 		newBuf := make([]byte, max(2*len(b.buf), b.pos+1))
@@ -51,6 +61,10 @@ func (b *BytesBuffer) Reset() {
 	b.pos = 0
 }
 
+func (b *BytesBuffer) Len() int {
+	return b.pos
+}
+
 func (b *BytesBuffer) Bytes() []byte {
 	return b.buf[:b.pos]
 }
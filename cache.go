@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"container/list"
 	"sync"
 )
 
@@ -11,88 +10,155 @@ type CacheItem struct {
 	value any
 }
 
-// LRUCache is a high-performance LRU cache
-type LRUCache struct {
-	items    map[string]*list.Element
-	list     *list.List
+// lruItem is an entry of the LRU list
+type lruItem[K comparable, V any] struct {
+	key   K
+	value V
+	prev  *lruItem[K, V]
+	next  *lruItem[K, V]
+}
+
+// LRU is a high-performance typed LRU cache, safe for concurrent use
+type LRU[K comparable, V any] struct {
+	items    map[K]*lruItem[K, V]
+	root     lruItem[K, V] // sentinel, root.next is the most recently used item
 	mu       sync.RWMutex
 	capacity int
 }
 
-// NewLRUCache creates a new LRU cache with pre-allocated capacity
-func NewLRUCache(capacity int) *LRUCache {
+// NewLRU creates a new LRU cache with pre-allocated capacity
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
 	if capacity < 1 {
 		capacity = 16
 	}
-	return &LRUCache{
-		items:    make(map[string]*list.Element, capacity+1), // +1 to avoid immediate resize
-		list:     list.New(),
+	c := &LRU[K, V]{
+		items:    make(map[K]*lruItem[K, V], capacity+1), // +1 to avoid immediate resize
 		capacity: capacity,
 	}
+	c.root.prev = &c.root
+	c.root.next = &c.root
+	return c
 }
 
 // Set adds or updates a key-value pair in the cache
-func (c *LRUCache) Set(key string, value any) {
+func (c *LRU[K, V]) Set(key K, value V) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	// Update existing key
-	if elem, ok := c.items[key]; ok {
-		c.list.MoveToFront(elem)
-		elem.Value.(*CacheItem).value = value
+	if item, ok := c.items[key]; ok {
+		c.moveToFront(item)
+		item.value = value
 		return
 	}
 
 	// Evict LRU item if capacity is reached
-	if c.list.Len() >= c.capacity {
-		if lru := c.list.Back(); lru != nil {
-			lruItem := lru.Value.(*CacheItem)
-			delete(c.items, lruItem.key)
-			c.list.Remove(lru)
+	if len(c.items) >= c.capacity {
+		if lru := c.root.prev; lru != &c.root {
+			delete(c.items, lru.key)
+			c.unlink(lru)
 		}
 	}
 
 	// Add new item
-	item := &CacheItem{key: key, value: value}
-	elem := c.list.PushFront(item)
-	c.items[key] = elem
+	item := &lruItem[K, V]{key: key, value: value}
+	c.pushFront(item)
+	c.items[key] = item
 }
 
 // Get retrieves a value from the cache
 // Returns: value, exists
-func (c *LRUCache) Get(key string) (any, bool) {
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	var zero V
+
 	c.mu.RLock()
 
 	if _, ok := c.items[key]; !ok {
 		c.mu.RUnlock()
-		return nil, false
+		return zero, false
 	}
 
 	// Move to front requires write lock
 	c.mu.RUnlock()
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if elem, ok := c.items[key]; ok { // Re-check in case of concurrent modification
-		c.list.MoveToFront(elem)
-		return elem.Value.(*CacheItem).value, true
+	if item, ok := c.items[key]; ok { // Re-check in case of concurrent modification
+		c.moveToFront(item)
+		return item.value, true
 	}
-	return nil, false
+	return zero, false
 }
 
 // Delete removes a key from the cache
-func (c *LRUCache) Delete(key string) {
+func (c *LRU[K, V]) Delete(key K) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if elem, ok := c.items[key]; ok {
+	if item, ok := c.items[key]; ok {
 		delete(c.items, key)
-		c.list.Remove(elem)
+		c.unlink(item)
 	}
 }
 
 // Len returns the current number of items in the cache
-func (c *LRUCache) Len() int {
+func (c *LRU[K, V]) Len() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.list.Len()
+	return len(c.items)
+}
+
+// pushFront inserts item as the most recently used
+func (c *LRU[K, V]) pushFront(item *lruItem[K, V]) {
+	item.prev = &c.root
+	item.next = c.root.next
+	item.prev.next = item
+	item.next.prev = item
+}
+
+// unlink removes item from the list
+func (c *LRU[K, V]) unlink(item *lruItem[K, V]) {
+	item.prev.next = item.next
+	item.next.prev = item.prev
+	item.prev = nil
+	item.next = nil
+}
+
+// moveToFront marks item as the most recently used
+func (c *LRU[K, V]) moveToFront(item *lruItem[K, V]) {
+	if c.root.next == item {
+		return
+	}
+	c.unlink(item)
+	c.pushFront(item)
+}
+
+// LRUCache is a high-performance LRU cache of any values under string keys
+type LRUCache struct {
+	lru *LRU[string, any]
+}
+
+// NewLRUCache creates a new LRU cache with pre-allocated capacity
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{lru: NewLRU[string, any](capacity)}
+}
+
+// Set adds or updates a key-value pair in the cache
+func (c *LRUCache) Set(key string, value any) {
+	c.lru.Set(key, value)
+}
+
+// Get retrieves a value from the cache
+// Returns: value, exists
+func (c *LRUCache) Get(key string) (any, bool) {
+	return c.lru.Get(key)
+}
+
+// Delete removes a key from the cache
+func (c *LRUCache) Delete(key string) {
+	c.lru.Delete(key)
+}
+
+// Len returns the current number of items in the cache
+func (c *LRUCache) Len() int {
+	return c.lru.Len()
 }
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestLRU(t *testing.T) {
+	c := NewLRU[int, string](2)
+
+	c.Set(1, "one")
+	c.Set(2, "two")
+
+	// 1 becomes the most recently used, so 2 is evicted
+	if v, ok := c.Get(1); !ok || v != "one" {
+		t.Errorf("expected one, got %q %v", v, ok)
+	}
+
+	c.Set(3, "three")
+
+	if _, ok := c.Get(2); ok {
+		t.Error("expected 2 to be evicted")
+	}
+
+	c.Set(1, "uno")
+	c.Set(4, "four")
+
+	tests := []struct {
+		key   int
+		value string
+		ok    bool
+	}{
+		{1, "uno", true},
+		{3, "", false},
+		{4, "four", true},
+	}
+
+	for _, tt := range tests {
+		if v, ok := c.Get(tt.key); v != tt.value || ok != tt.ok {
+			t.Errorf("%d: expected %q %v, got %q %v", tt.key, tt.value, tt.ok, v, ok)
+		}
+	}
+
+	c.Delete(1)
+	c.Delete(5)
+
+	if n := c.Len(); n != 1 {
+		t.Errorf("expected 1 item, got %d", n)
+	}
+}
+
+func TestLRUCacheEviction(t *testing.T) {
+	c := NewLRUCache(2)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	// a becomes the most recently used, so b is evicted
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("expected 1, got %v %v", v, ok)
+	}
+
+	c.Set("c", 3)
+
+	tests := []struct {
+		key   string
+		value any
+		ok    bool
+	}{
+		{"a", 1, true},
+		{"b", nil, false},
+		{"c", 3, true},
+	}
+
+	for _, tt := range tests {
+		if v, ok := c.Get(tt.key); v != tt.value || ok != tt.ok {
+			t.Errorf("%s: expected %v %v, got %v %v", tt.key, tt.value, tt.ok, v, ok)
+		}
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(0)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := strconv.Itoa(i*100 + j)
+				c.Set(key, j)
+				if v, ok := c.Get(key); ok && v.(int) != j {
+					t.Errorf("%s: expected %d, got %v", key, j, v)
+				}
+				if j%3 == 0 {
+					c.Delete(key)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if n := c.Len(); n > 16 {
+		t.Errorf("expected at most the default capacity of 16, got %d", n)
+	}
+}
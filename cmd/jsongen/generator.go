@@ -0,0 +1,636 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const utilsPath = "pkg.gostartkit.com/utils"
+
+// basicKinds maps the basic types jsongen encodes itself to their kind
+var basicKinds = map[string]string{
+	"string":  "string",
+	"bool":    "bool",
+	"int":     "int",
+	"int8":    "int8",
+	"int16":   "int16",
+	"int32":   "int32",
+	"rune":    "int32",
+	"int64":   "int64",
+	"uint":    "uint",
+	"uint8":   "uint8",
+	"byte":    "uint8",
+	"uint16":  "uint16",
+	"uint32":  "uint32",
+	"uint64":  "uint64",
+	"float32": "float32",
+	"float64": "float64",
+}
+
+// field is an exported struct field with its json tag applied
+type field struct {
+	name      string
+	key       string
+	typ       ast.Expr
+	omitEmpty bool
+}
+
+type generator struct {
+	buf     bytes.Buffer
+	pkg     string
+	q       string // qualifier for utils identifiers
+	types   map[string]ast.Expr
+	queue   []string
+	done    map[string]bool
+	vars    int
+	imports map[string]bool
+}
+
+// generate parses files and returns the formatted source for the named struct types
+func generate(files []string, names []string) ([]byte, error) {
+
+	g := &generator{
+		q:       "utils.",
+		types:   map[string]ast.Expr{},
+		done:    map[string]bool{},
+		imports: map[string]bool{},
+	}
+
+	fset := token.NewFileSet()
+
+	for _, file := range files {
+
+		f, err := parser.ParseFile(fset, file, nil, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if g.pkg == "" {
+			g.pkg = f.Name.Name
+		} else if g.pkg != f.Name.Name {
+			return nil, fmt.Errorf("files belong to packages %s and %s", g.pkg, f.Name.Name)
+		}
+
+		for _, decl := range f.Decls {
+
+			gen, ok := decl.(*ast.GenDecl)
+
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+
+				ts := spec.(*ast.TypeSpec)
+
+				if ts.TypeParams != nil || ts.Assign.IsValid() {
+					continue
+				}
+
+				g.types[ts.Name.Name] = ts.Type
+			}
+		}
+	}
+
+	if isUtilsPackage(filepath.Dir(files[0])) {
+		g.q = ""
+	} else {
+		g.imports[utilsPath] = true
+	}
+
+	for _, name := range names {
+
+		name = strings.TrimSpace(name)
+
+		if _, ok := g.types[name].(*ast.StructType); !ok {
+			return nil, fmt.Errorf("type %s is not a struct declared in the files", name)
+		}
+
+		g.queue = append(g.queue, name)
+	}
+
+	for len(g.queue) > 0 {
+
+		name := g.queue[0]
+		g.queue = g.queue[1:]
+
+		if g.done[name] {
+			continue
+		}
+
+		g.done[name] = true
+
+		if err := g.genType(name, g.types[name].(*ast.StructType)); err != nil {
+			return nil, err
+		}
+	}
+
+	var src bytes.Buffer
+
+	fmt.Fprintf(&src, "// Code generated by jsongen. DO NOT EDIT.\n\npackage %s\n", g.pkg)
+
+	imports := make([]string, 0, len(g.imports))
+
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+
+	sort.Strings(imports)
+
+	if len(imports) > 0 {
+		src.WriteString("\nimport (\n")
+		for _, path := range imports {
+			fmt.Fprintf(&src, "%q\n", path)
+		}
+		src.WriteString(")\n")
+	}
+	src.Write(g.buf.Bytes())
+
+	out, err := format.Source(src.Bytes())
+
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %v", err)
+	}
+
+	return out, nil
+}
+
+// isUtilsPackage reports whether dir is the root of the utils module itself
+func isUtilsPackage(dir string) bool {
+
+	f, err := os.Open(filepath.Join(dir, "go.mod"))
+
+	if err != nil {
+		return false
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 2 && fields[0] == "module" {
+			return fields[1] == utilsPath
+		}
+	}
+
+	return false
+}
+
+// p writes formatted code
+func (g *generator) p(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// tmp returns a fresh variable name
+func (g *generator) tmp(prefix string) string {
+	g.vars++
+	return prefix + strconv.Itoa(g.vars)
+}
+
+// fields returns the encoded fields of st
+func (g *generator) fields(name string, st *ast.StructType) ([]field, error) {
+
+	var fields []field
+
+	for _, f := range st.Fields.List {
+
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded field %s is not supported", name, types.ExprString(f.Type))
+		}
+
+		var tag string
+
+		if f.Tag != nil {
+			raw, err := strconv.Unquote(f.Tag.Value)
+
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid tag %s", name, f.Tag.Value)
+			}
+
+			tag = reflect.StructTag(raw).Get("json")
+		}
+
+		if tag == "-" {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+
+		omitEmpty := false
+
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitEmpty = true
+			}
+		}
+
+		for _, n := range f.Names {
+
+			if !n.IsExported() {
+				continue
+			}
+
+			key := parts[0]
+
+			if key == "" {
+				key = n.Name
+			}
+
+			fields = append(fields, field{name: n.Name, key: key, typ: f.Type, omitEmpty: omitEmpty})
+		}
+	}
+
+	return fields, nil
+}
+
+// genType writes the methods of the struct type name
+func (g *generator) genType(name string, st *ast.StructType) error {
+
+	fields, err := g.fields(name, st)
+
+	if err != nil {
+		return err
+	}
+
+	// a value receiver lets encoding/json use MarshalJSON for values and fields, not only pointers
+	g.p("\n// MarshalJSON implements json.Marshaler\n")
+	g.p("func (o %s) MarshalJSON() ([]byte, error) {\n", name)
+	g.p("w := %sCreateJSONWriter()\n", g.q)
+	g.p("defer %sReleaseJSONWriter(w)\n", g.q)
+	g.p("if err := o.EncodeJSON(w); err != nil {\nreturn nil, err\n}\n")
+	g.p("return append([]byte(nil), w.Bytes()...), nil\n}\n")
+
+	g.p("\n// EncodeJSON writes o to w\n")
+	g.p("func (o *%s) EncodeJSON(w *%sJSONWriter) error {\n", name, g.q)
+	g.p("if err := w.BeginObject(); err != nil {\nreturn err\n}\n")
+
+	for _, f := range fields {
+
+		x := "o." + f.name
+
+		cond := ""
+
+		if f.omitEmpty {
+			cond = g.nonEmpty(x, f.typ)
+		}
+
+		if cond != "" {
+			g.p("if %s {\n", cond)
+		}
+
+		g.p("if err := w.Key(%q); err != nil {\nreturn err\n}\n", f.key)
+
+		if g.supported(f.typ) {
+			g.encode(x, f.typ)
+		} else {
+			g.encodeFallback(x)
+		}
+
+		if cond != "" {
+			g.p("}\n")
+		}
+	}
+
+	g.p("return w.EndObject()\n}\n")
+
+	g.p("\n// UnmarshalJSON implements json.Unmarshaler\n")
+	g.p("func (o *%s) UnmarshalJSON(data []byte) error {\n", name)
+	g.p("l := %sCreateJSONLexer(data)\n", g.q)
+	g.p("defer %sReleaseJSONLexer(l)\n", g.q)
+	g.p("l.SkipWhitespace()\n")
+	g.p("if l.Peek() == 'n' {\nif err := l.ReadNull(); err != nil {\nreturn err\n}\nreturn l.ExpectEOF()\n}\n")
+	g.p("if err := o.DecodeJSON(l); err != nil {\nreturn err\n}\n")
+	g.p("return l.ExpectEOF()\n}\n")
+
+	g.p("\n// DecodeJSON reads o from l, unknown keys are skipped\n")
+	g.p("// Keys are matched like encoding/json, preferring an exact match but also accepting a case-insensitive one\n")
+	g.p("func (o *%s) DecodeJSON(l *%sJSONLexer) error {\n", name, g.q)
+	g.p("if err := l.Expect('{'); err != nil {\nreturn err\n}\n")
+	g.p("l.SkipWhitespace()\n")
+	g.p("if l.Peek() == '}' {\nl.Advance()\nreturn nil\n}\n")
+	g.p("for {\n")
+	g.p("key, err := l.ReadKeyBytes()\nif err != nil {\nreturn err\n}\n")
+	g.p("if err := l.Expect(':'); err != nil {\nreturn err\n}\n")
+
+	// resolve the key to a field first so each field is decoded once
+	g.p("field := 0\n")
+	g.p("switch string(key) {\n")
+
+	for i, f := range fields {
+		g.p("case %q:\nfield = %d\n", f.key, i+1)
+	}
+
+	if len(fields) > 0 {
+		g.imports["bytes"] = true
+		g.p("default:\nswitch {\n")
+		for i, f := range fields {
+			g.p("case bytes.EqualFold(key, []byte(%q)):\nfield = %d\n", f.key, i+1)
+		}
+		g.p("}\n")
+	}
+
+	g.p("}\n")
+	g.p("switch field {\n")
+
+	for i, f := range fields {
+
+		g.p("case %d:\n", i+1)
+
+		x := "o." + f.name
+
+		if g.supported(f.typ) {
+			g.decode(x, f.typ)
+		} else {
+			g.decodeFallback(x)
+		}
+	}
+
+	g.p("default:\nif err := l.SkipValue(); err != nil {\nreturn err\n}\n}\n")
+	g.p("l.SkipWhitespace()\n")
+	g.p("if l.Peek() == '}' {\nl.Advance()\nreturn nil\n}\n")
+	g.p("if err := l.Expect(','); err != nil {\nreturn err\n}\n")
+	g.p("}\n}\n")
+
+	return nil
+}
+
+// kind returns the basic kind of typ, following local named types
+func (g *generator) kind(typ ast.Expr) string {
+
+	ident, ok := typ.(*ast.Ident)
+
+	if !ok {
+		return ""
+	}
+
+	if local, ok := g.types[ident.Name]; ok {
+		return g.kind(local)
+	}
+
+	return basicKinds[ident.Name]
+}
+
+// isStruct reports whether typ names a local struct type
+func (g *generator) isStruct(typ ast.Expr) bool {
+
+	ident, ok := typ.(*ast.Ident)
+
+	if !ok {
+		return false
+	}
+
+	_, ok = g.types[ident.Name].(*ast.StructType)
+
+	return ok
+}
+
+// supported reports whether typ can be generated without naming other packages
+func (g *generator) supported(typ ast.Expr) bool {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		_, local := g.types[t.Name]
+		return local || basicKinds[t.Name] != ""
+	case *ast.StarExpr:
+		return g.supported(t.X)
+	case *ast.ArrayType:
+		return t.Len == nil && g.supported(t.Elt)
+	default:
+		return false
+	}
+}
+
+// nonEmpty returns the condition under which an omitempty field is written
+func (g *generator) nonEmpty(x string, typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.StarExpr, *ast.InterfaceType:
+		return x + " != nil"
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "len(" + x + ") != 0"
+		}
+		return ""
+	case *ast.MapType:
+		return "len(" + x + ") != 0"
+	case *ast.Ident:
+		if t.Name == "any" || t.Name == "error" {
+			return x + " != nil"
+		}
+		if local, ok := g.types[t.Name]; ok && !g.isStruct(t) {
+			return g.nonEmpty(x, local)
+		}
+	}
+
+	switch g.kind(typ) {
+	case "":
+		return ""
+	case "string":
+		return x + ` != ""`
+	case "bool":
+		return x
+	default:
+		return x + " != 0"
+	}
+}
+
+// encode writes code encoding the value x of type typ
+func (g *generator) encode(x string, typ ast.Expr) {
+	switch t := typ.(type) {
+	case *ast.StarExpr:
+		g.p("if %s == nil {\nif err := w.WriteNull(); err != nil {\nreturn err\n}\n} else {\n", x)
+		g.encode("(*"+x+")", t.X)
+		g.p("}\n")
+		return
+	case *ast.ArrayType:
+		if g.kind(t.Elt) == "uint8" {
+			g.encodeFallback(x)
+			return
+		}
+		e := g.tmp("e")
+		g.p("if %s == nil {\nif err := w.WriteNull(); err != nil {\nreturn err\n}\n} else {\n", x)
+		g.p("if err := w.BeginArray(); err != nil {\nreturn err\n}\n")
+		g.p("for _, %s := range %s {\n", e, x)
+		g.encode(e, t.Elt)
+		g.p("}\n")
+		g.p("if err := w.EndArray(); err != nil {\nreturn err\n}\n")
+		g.p("}\n")
+		return
+	}
+
+	if g.isStruct(typ) {
+		g.enqueue(typ)
+		g.p("if err := %s.EncodeJSON(w); err != nil {\nreturn err\n}\n", x)
+		return
+	}
+
+	name := types.ExprString(typ)
+
+	var method, conv string
+
+	switch g.kind(typ) {
+	case "string":
+		method, conv = "WriteString", "string"
+	case "bool":
+		method, conv = "WriteBool", "bool"
+	case "int", "int8", "int16", "int32", "int64":
+		method, conv = "WriteInt64", "int64"
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		method, conv = "WriteUint64", "uint64"
+	case "float32":
+		method, conv = "WriteFloat32", "float32"
+	case "float64":
+		method, conv = "WriteFloat64", "float64"
+	default:
+		g.encodeFallback(x)
+		return
+	}
+
+	if name != conv {
+		x = conv + "(" + x + ")"
+	}
+
+	g.p("if err := w.%s(%s); err != nil {\nreturn err\n}\n", method, x)
+}
+
+// encodeFallback writes code encoding x with encoding/json
+func (g *generator) encodeFallback(x string) {
+	g.imports["encoding/json"] = true
+	b := g.tmp("b")
+	g.p("%s, err := json.Marshal(%s)\nif err != nil {\nreturn err\n}\n", b, x)
+	g.p("if err := w.WriteRaw(%s); err != nil {\nreturn err\n}\n", b)
+}
+
+// decode writes code decoding into x of type typ, null leaves scalars unchanged and clears pointers and slices
+func (g *generator) decode(x string, typ ast.Expr) {
+
+	if arr, ok := typ.(*ast.ArrayType); ok && g.kind(arr.Elt) == "uint8" {
+		g.decodeFallback(x)
+		return
+	}
+
+	g.p("l.SkipWhitespace()\n")
+	g.p("if l.Peek() == 'n' {\nif err := l.ReadNull(); err != nil {\nreturn err\n}\n")
+
+	switch typ.(type) {
+	case *ast.StarExpr, *ast.ArrayType:
+		g.p("%s = nil\n", x)
+	}
+
+	g.p("} else {\n")
+	g.decodeValue(x, typ)
+	g.p("}\n")
+}
+
+// decodeValue writes code decoding a non-null value into x of type typ
+func (g *generator) decodeValue(x string, typ ast.Expr) {
+	switch t := typ.(type) {
+	case *ast.StarExpr:
+		g.p("if %s == nil {\n%s = new(%s)\n}\n", x, x, types.ExprString(t.X))
+		g.decodeValue("(*"+x+")", t.X)
+		return
+	case *ast.ArrayType:
+		name := types.ExprString(t)
+		e := g.tmp("e")
+		g.p("if err := l.Expect('['); err != nil {\nreturn err\n}\n")
+		g.p("if %s == nil {\n%s = make(%s, 0)\n} else {\n%s = %s[:0]\n}\n", x, x, name, x, x)
+		g.p("l.SkipWhitespace()\n")
+		g.p("if l.Peek() == ']' {\nl.Advance()\n} else {\n")
+		g.p("for {\n")
+		g.p("var %s %s\n", e, types.ExprString(t.Elt))
+		g.decode(e, t.Elt)
+		g.p("%s = append(%s, %s)\n", x, x, e)
+		g.p("l.SkipWhitespace()\n")
+		g.p("if l.Peek() == ']' {\nl.Advance()\nbreak\n}\n")
+		g.p("if err := l.Expect(','); err != nil {\nreturn err\n}\n")
+		g.p("}\n}\n")
+		return
+	}
+
+	if g.isStruct(typ) {
+		g.enqueue(typ)
+		g.p("if err := %s.DecodeJSON(l); err != nil {\nreturn err\n}\n", x)
+		return
+	}
+
+	name := types.ExprString(typ)
+	kind := g.kind(typ)
+
+	var method, conv string
+
+	switch kind {
+	case "string":
+		method, conv = "ReadString", "string"
+	case "bool":
+		method, conv = "ReadBool", "bool"
+	case "int":
+		method, conv = "ReadInt", "int"
+	case "int8", "int16", "int32", "int64":
+		method, conv = "ReadInt64", "int64"
+	case "uint":
+		method, conv = "ReadUint", "uint"
+	case "uint8", "uint16", "uint32", "uint64":
+		method, conv = "ReadUint64", "uint64"
+	case "float32", "float64":
+		method, conv = "ReadNumber", "float64"
+	default:
+		g.decodeFallback(x)
+		return
+	}
+
+	v := g.tmp("v")
+
+	g.p("%s, err := l.%s()\nif err != nil {\nreturn err\n}\n", v, method)
+
+	switch kind {
+	case "int8", "int16", "int32":
+		g.imports["fmt"] = true
+		g.imports["math"] = true
+		bits := strings.TrimPrefix(kind, "int")
+		g.p("if %s < math.MinInt%s || %s > math.MaxInt%s {\n", v, bits, v, bits)
+		g.p("return fmt.Errorf(\"value %%d out of range for %s\", %s)\n}\n", kind, v)
+	case "uint8", "uint16", "uint32":
+		g.imports["fmt"] = true
+		g.imports["math"] = true
+		bits := strings.TrimPrefix(kind, "uint")
+		g.p("if %s > math.MaxUint%s {\n", v, bits)
+		g.p("return fmt.Errorf(\"value %%d out of range for %s\", %s)\n}\n", kind, v)
+	}
+
+	if name != conv {
+		v = name + "(" + v + ")"
+	}
+
+	g.p("%s = %s\n", x, v)
+}
+
+// decodeFallback writes code decoding into x with encoding/json
+func (g *generator) decodeFallback(x string) {
+	g.imports["encoding/json"] = true
+	raw := g.tmp("raw")
+	g.p("%s, err := l.ReadRawValue()\nif err != nil {\nreturn err\n}\n", raw)
+	g.p("if err := json.Unmarshal(%s, &%s); err != nil {\nreturn err\n}\n", raw, x)
+}
+
+// enqueue schedules generation of the local struct type typ
+func (g *generator) enqueue(typ ast.Expr) {
+	name := typ.(*ast.Ident).Name
+
+	if !g.done[name] {
+		g.queue = append(g.queue, name)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := generate([]string{"testdata/sample.go"}, []string{"Outer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "sample_json.go", src, 0); err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, src)
+	}
+
+	for _, want := range []string{
+		"func (o Outer) MarshalJSON() ([]byte, error)",
+		"func (o *Outer) UnmarshalJSON(data []byte) error",
+		"func (o *Inner) DecodeJSON(l *utils.JSONLexer) error",
+		"key, err := l.ReadKeyBytes()",
+		`case "pinner":`,
+		`case bytes.EqualFold(key, []byte("pinner")):`,
+		"if o.Count != 0 {",
+		"if len(o.Tags) != 0 {",
+		"json.Unmarshal(raw",
+		"if err := l.SkipValue(); err != nil {",
+		"return l.ExpectEOF()",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code does not contain %q", want)
+		}
+	}
+
+	for _, unwanted := range []string{`"Skip"`, `"private"`, `"-"`} {
+		if strings.Contains(string(src), unwanted) {
+			t.Errorf("generated code contains %s", unwanted)
+		}
+	}
+}
+
+func TestGenerateNotStruct(t *testing.T) {
+	if _, err := generate([]string{"testdata/sample.go"}, []string{"Status"}); err == nil {
+		t.Error("expected error for non-struct type")
+	}
+}
+
+// roundTripMain checks the generated methods of Outer against encoding/json
+const roundTripMain = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// plain has the fields of Outer without its methods, so encoding/json uses reflection
+type plain Outer
+
+// values implement json.Marshaler, so encoding/json does not fall back to reflection
+var _ json.Marshaler = Outer{}
+
+func check(name string, got, want any) {
+	if !reflect.DeepEqual(got, want) {
+		fmt.Printf("%s: got %v, want %v\n", name, got, want)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	s := "x"
+	v := Outer{
+		ID: 1, Count: 2, Ratio: 0.1, OK: true, Status: -3, Small: 65535, Ptr: &s,
+		Inner:  Inner{Name: "in", Tags: []string{"a", "b"}},
+		PInner: &Inner{Name: "p"},
+		Items:  []Inner{{Name: "i"}},
+		PItems: []*Inner{nil, {Name: "q"}},
+		Matrix: [][]int64{{1, 2}, {}},
+		Data:   []byte("data"),
+		When:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Meta:   map[string]any{"k": "v"},
+		NoTag:  "t",
+	}
+
+	// MarshalJSON is used for values and fields, not only pointers
+	got, err := json.Marshal(v)
+	check("marshal error", err, nil)
+	want, _ := json.Marshal(plain(v))
+	check("marshal", string(got), string(want))
+
+	wrapped, err := json.Marshal(struct{ O Outer }{v})
+	check("field error", err, nil)
+	check("field", string(wrapped), "{\"O\":"+string(want)+"}")
+
+	var decoded Outer
+	check("unmarshal error", json.Unmarshal(got, &decoded), nil)
+	check("unmarshal", decoded, v)
+
+	// keys match case-insensitively, an exact match wins
+	doc := []byte("{\"ID\": 7, \"iNNer\": {\"NAME\": \"n\"}, \"notag\": \"u\", \"NoTag\": \"w\", \"unknown\": [1]}")
+	var a Outer
+	var b plain
+	check("fold error", json.Unmarshal(doc, &a), nil)
+	check("fold reflect error", json.Unmarshal(doc, &b), nil)
+	check("fold", plain(a), b)
+}
+`
+
+func TestGenerateRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	dir, err := os.MkdirTemp("testdata", "roundtrip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sample, err := os.ReadFile("testdata/sample.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sample = bytes.Replace(sample, []byte("package sample"), []byte("package main"), 1)
+
+	files := map[string][]byte{"sample.go": sample, "main.go": []byte(roundTripMain)}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src, err := generate([]string{filepath.Join(dir, "sample.go")}, []string{"Outer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "sample_json.go"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// building type-checks the generated file
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
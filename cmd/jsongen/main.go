@@ -0,0 +1,59 @@
+// Command jsongen generates reflection-free JSON methods for Go struct types
+//
+// Usage:
+//
+//	//go:generate go run pkg.gostartkit.com/utils/cmd/jsongen -type Auth model.go
+//
+// For each type it emits MarshalJSON and EncodeJSON driven by utils.JSONWriter,
+// and UnmarshalJSON and DecodeJSON driven by utils.JSONLexer.
+// Nested struct types declared in the same files are generated as well.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of type names, required")
+	output := flag.String("output", "", "output file name, default <first file>_json.go")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: jsongen -type T[,T...] [-output file] file.go...\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	files := flag.Args()
+
+	if len(files) == 0 {
+		if gofile := os.Getenv("GOFILE"); gofile != "" {
+			files = []string{gofile}
+		}
+	}
+
+	if *typeNames == "" || len(files) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(files[0], ".go") + "_json.go"
+	}
+
+	src, err := generate(files, strings.Split(*typeNames, ","))
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jsongen: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(filepath.Clean(*output), src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "jsongen: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,34 @@
+package sample
+
+import "time"
+
+type Status int8
+
+type Inner struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+type Empty struct{}
+
+type Outer struct {
+	ID      uint64         `json:"id"`
+	Count   int            `json:"count,omitempty"`
+	Ratio   float32        `json:"ratio"`
+	OK      bool           `json:"ok"`
+	Status  Status         `json:"status"`
+	Small   uint16         `json:"small"`
+	Ptr     *string        `json:"ptr"`
+	Inner   Inner          `json:"inner"`
+	PInner  *Inner         `json:"pinner,omitempty"`
+	Items   []Inner        `json:"items"`
+	PItems  []*Inner       `json:"pitems"`
+	Matrix  [][]int64      `json:"matrix"`
+	Data    []byte         `json:"data"`
+	When    time.Time      `json:"when"`
+	Meta    map[string]any `json:"meta,omitempty"`
+	Empty   Empty          `json:"empty"`
+	Skip    string         `json:"-"`
+	NoTag   string
+	private int
+}
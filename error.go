@@ -25,8 +25,50 @@ var (
 	ErrOrderByInvalid = errors.New("orderby invalid")
 	// ErrEcPublicKeyInvalid ec public key invalid
 	ErrEcPublicKeyInvalid = errors.New("ec public key invalid")
+	// ErrSignatureInvalid signature invalid
+	ErrSignatureInvalid = errors.New("signature invalid")
 	// ErrPemBlockInvalid pem block invalid
 	ErrPemBlockInvalid = errors.New("pem block invalid")
 	// ErrCredentialsInvalid login failed, please check your credentials
 	ErrCredentialsInvalid = errors.New("login failed, please check your credentials")
+	// ErrJSONPointerInvalid json pointer invalid
+	ErrJSONPointerInvalid = errors.New("json pointer invalid")
+	// ErrJSONPointerNotFound json pointer not found
+	ErrJSONPointerNotFound = errors.New("json pointer not found")
+	// ErrJSONLineTooLong json line too long
+	ErrJSONLineTooLong = errors.New("json line too long")
+	// ErrNDJSONNewline ndjson record contains a newline
+	ErrNDJSONNewline = errors.New("ndjson record contains a newline")
+	// ErrJSONStop returned by a callback stops the iteration without error
+	ErrJSONStop = errors.New("json iteration stopped")
+	// ErrJSONPatchInvalid json patch invalid
+	ErrJSONPatchInvalid = errors.New("json patch invalid")
+	// ErrJSONPatchTestFailed json patch test failed
+	ErrJSONPatchTestFailed = errors.New("json patch test failed")
+	// ErrJSONSchemaInvalid json schema invalid
+	ErrJSONSchemaInvalid = errors.New("json schema invalid")
+	// ErrJSONTooDeep json nesting too deep
+	ErrJSONTooDeep = errors.New("json nesting too deep")
+	// ErrJSONStringTooLong json string too long
+	ErrJSONStringTooLong = errors.New("json string too long")
+	// ErrJSONTooManyTokens json has too many tokens
+	ErrJSONTooManyTokens = errors.New("json has too many tokens")
+	// ErrCBORInvalid cbor invalid
+	ErrCBORInvalid = errors.New("cbor invalid")
+	// ErrMsgPackInvalid msgpack invalid
+	ErrMsgPackInvalid = errors.New("msgpack invalid")
+	// ErrJSONNotInteger json number is not an integer
+	ErrJSONNotInteger = errors.New("expected integer, got float")
+	// ErrJSONIntegerRange json integer out of range
+	ErrJSONIntegerRange = errors.New("integer out of range")
+	// ErrJSONWriterKey json writer key outside object
+	ErrJSONWriterKey = errors.New("json writer: key outside object")
+	// ErrJSONWriterValue json writer value in object without key
+	ErrJSONWriterValue = errors.New("json writer: value in object without key")
+	// ErrJSONWriterEnd json writer end does not match begin
+	ErrJSONWriterEnd = errors.New("json writer: end does not match begin")
+	// ErrJSONWriterComplete json writer value after complete document
+	ErrJSONWriterComplete = errors.New("json writer: value after complete document")
+	// ErrJSONWriterFloat json writer unsupported float value
+	ErrJSONWriterFloat = errors.New("json writer: unsupported float value")
 )
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -20,6 +22,72 @@ func ReadJSON(filename string, v any) error {
 	return json.NewDecoder(f).Decode(v)
 }
 
+// ReadJSONC read json with comments to data
+// The file may use the extensions accepted by JSONLexer.SetLenient
+func ReadJSONC(filename string, v any) error {
+
+	data, err := os.ReadFile(filename)
+
+	if err != nil {
+		return err
+	}
+
+	data, err = StripJSONC(data)
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// StripJSONC converts lenient JSON, see JSONLexer.SetLenient, to compact standard JSON
+func StripJSONC(data []byte) ([]byte, error) {
+
+	l := CreateJSONLexer(data)
+	defer ReleaseJSONLexer(l)
+
+	l.SetLenient(true)
+
+	w := CreateJSONWriter()
+	defer ReleaseJSONWriter(w)
+
+	for {
+		tok, err := l.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok.Kind {
+		case JSONTokenObjectStart:
+			err = w.BeginObject()
+		case JSONTokenObjectEnd:
+			err = w.EndObject()
+		case JSONTokenArrayStart:
+			err = w.BeginArray()
+		case JSONTokenArrayEnd:
+			err = w.EndArray()
+		case JSONTokenKey:
+			err = w.Key(tok.Value)
+		case JSONTokenString:
+			err = w.WriteString(tok.Value)
+		default:
+			err = w.WriteRaw(tok.Raw)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return append([]byte(nil), w.Bytes()...), nil
+}
+
 // WriteJSON write data to json
 func WriteJSON(filename string, v any, overwrite bool) error {
 
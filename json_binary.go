@@ -0,0 +1,260 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+const (
+	// longest container header of the binary formats, reserved until the count is known
+	jsonBinaryHeadMax = 9
+)
+
+// jsonBinaryFormat appends JSON values in a binary encoding
+type jsonBinaryFormat interface {
+	appendNull(dst []byte) []byte
+	appendBool(dst []byte, v bool) []byte
+	appendInt(dst []byte, v int64) []byte
+	appendUint(dst []byte, v uint64) []byte
+	appendFloat(dst []byte, v float64) []byte
+	appendString(dst []byte, v string) []byte
+	appendArray(dst []byte, n uint64) []byte
+	appendMap(dst []byte, n uint64) []byte
+}
+
+// transcodeJSON re-encodes the JSON document data with format
+func transcodeJSON(data []byte, format jsonBinaryFormat) ([]byte, error) {
+
+	l := CreateJSONLexer(data)
+	defer ReleaseJSONLexer(l)
+
+	out, err := appendJSONBinary(make([]byte, 0, len(data)/2), l, format, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.ExpectEOF(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// appendJSONBinary appends the next value of l to dst in format
+// containers are written with a reserved header that is shrunk once the count is known
+func appendJSONBinary(dst []byte, l *JSONLexer, format jsonBinaryFormat, depth int) ([]byte, error) {
+
+	l.SkipWhitespace()
+
+	switch jsonKindOf(l.Peek()) {
+	case JSONTokenObjectStart, JSONTokenArrayStart:
+		if err := l.checkDepth(depth + 1); err != nil {
+			return nil, err
+		}
+		object := l.Peek() == _BraceLeft
+		closing := byte(_BracketRight)
+		if object {
+			closing = _BraceRight
+		}
+		l.Advance()
+		l.SkipWhitespace()
+		start := len(dst)
+		dst = append(dst, make([]byte, jsonBinaryHeadMax)...)
+		var n uint64
+		for l.Peek() != closing {
+			if n > 0 {
+				if err := l.Expect(_CommaChar); err != nil {
+					return nil, err
+				}
+			}
+			if object {
+				key, err := l.ReadString()
+				if err != nil {
+					return nil, err
+				}
+				if err := l.Expect(_ColonChar); err != nil {
+					return nil, err
+				}
+				dst = format.appendString(dst, key)
+			}
+			var err error
+			if dst, err = appendJSONBinary(dst, l, format, depth+1); err != nil {
+				return nil, err
+			}
+			n++
+			l.SkipWhitespace()
+		}
+		l.Advance()
+		var head [jsonBinaryHeadMax]byte
+		h := format.appendArray(head[:0], n)
+		if object {
+			h = format.appendMap(head[:0], n)
+		}
+		copy(dst[start+len(h):], dst[start+jsonBinaryHeadMax:])
+		copy(dst[start:], h)
+		return dst[:len(dst)-jsonBinaryHeadMax+len(h)], nil
+	case JSONTokenString:
+		s, err := l.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		return format.appendString(dst, s), nil
+	case JSONTokenNumber:
+		raw, err := l.ReadNumberRaw()
+		if err != nil {
+			return nil, err
+		}
+		// integer literals keep their exact value, others become floats
+		if !bytes.ContainsAny(raw, ".eE") {
+			if raw[0] == '-' {
+				if v, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+					return format.appendInt(dst, v), nil
+				}
+			} else if v, err := strconv.ParseUint(string(raw), 10, 64); err == nil {
+				return format.appendUint(dst, v), nil
+			}
+		}
+		f, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return nil, l.syntaxErrorMsg("invalid number", err)
+		}
+		return format.appendFloat(dst, f), nil
+	case JSONTokenBool:
+		b, err := l.ReadBool()
+		if err != nil {
+			return nil, err
+		}
+		return format.appendBool(dst, b), nil
+	case JSONTokenNull:
+		if err := l.ReadNull(); err != nil {
+			return nil, err
+		}
+		return format.appendNull(dst), nil
+	default:
+		return nil, l.syntaxError("value", l.got())
+	}
+}
+
+// isFloat32 reports whether f survives a round trip through float32
+func isFloat32(f float64) bool {
+	return float64(float32(f)) == f
+}
+
+// binaryInput reads a binary document being transcoded to JSON
+type binaryInput struct {
+	data    []byte
+	pos     int
+	name    string // format name for errors
+	invalid error  // sentinel wrapped by errors
+}
+
+// errorf returns an error at the current position wrapping the invalid sentinel
+func (in *binaryInput) errorf(format string, args ...any) error {
+	return fmt.Errorf("%s offset %d: %s: %w", in.name, in.pos, fmt.Sprintf(format, args...), in.invalid)
+}
+
+// byte reads one byte
+func (in *binaryInput) byte() (byte, error) {
+	if in.pos >= len(in.data) {
+		return 0, in.errorf("unexpected end of data")
+	}
+	c := in.data[in.pos]
+	in.pos++
+	return c, nil
+}
+
+// bytes reads n bytes, the slice refers to the input
+func (in *binaryInput) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(in.data)-in.pos) {
+		return nil, in.errorf("unexpected end of data")
+	}
+	b := in.data[in.pos : in.pos+int(n)]
+	in.pos += int(n)
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes
+func (in *binaryInput) uint(size int) (uint64, error) {
+	b, err := in.bytes(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// expectEnd checks the whole input was read
+func (in *binaryInput) expectEnd() error {
+	if in.pos < len(in.data) {
+		return in.errorf("unexpected data after value")
+	}
+	return nil
+}
+
+// checkDepth checks a nesting depth against the default JSON limit
+func (in *binaryInput) checkDepth(depth int) error {
+	if depth > jsonDefaultMaxDepth {
+		return fmt.Errorf("%s offset %d: %w", in.name, in.pos, ErrJSONTooDeep)
+	}
+	return nil
+}
+
+// writeFloat writes a decoded float, NaN and infinities have no JSON form and fail at start
+func (in *binaryInput) writeFloat(w *JSONWriter, f float64, start int) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		in.pos = start
+		return in.errorf("unsupported float value %v", f)
+	}
+	return w.WriteFloat64(f)
+}
+
+// writeBinaryBytes writes a byte string as unpadded base64url, as RFC 8949 section 6.1 suggests
+func writeBinaryBytes(w *JSONWriter, b []byte) error {
+	return w.WriteString(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// negativeUintString formats -1-n for n that may not fit in an int64
+func negativeUintString(n uint64) string {
+	if n <= math.MaxInt64 {
+		return strconv.FormatInt(-1-int64(n), 10)
+	}
+	v := new(big.Int).SetUint64(n)
+	v.Add(v, big.NewInt(1))
+	return "-" + v.String()
+}
+
+// appendUint16 appends v in big-endian order
+func appendUint16(dst []byte, v uint16) []byte {
+	return append(dst, byte(v>>8), byte(v))
+}
+
+// appendUint32 appends v in big-endian order
+func appendUint32(dst []byte, v uint32) []byte {
+	return append(dst, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// appendUint64 appends v in big-endian order
+func appendUint64(dst []byte, v uint64) []byte {
+	return appendUint32(appendUint32(dst, uint32(v>>32)), uint32(v))
+}
+
+// binaryToJSON runs decode on a pooled writer and returns a copy of the document
+func binaryToJSON(decode func(w *JSONWriter) error) ([]byte, error) {
+
+	w := CreateJSONWriter()
+	defer ReleaseJSONWriter(w)
+
+	if err := decode(w); err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), w.Bytes()...), nil
+}
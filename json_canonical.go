@@ -0,0 +1,290 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// jsonCanonicalMember is an object member waiting to be sorted
+type jsonCanonicalMember struct {
+	key   string
+	value []byte
+}
+
+// CanonicalJSON re-emits data in the RFC 8785 JSON Canonicalization Scheme
+// Object keys are sorted by UTF-16 code units, numbers use the ECMAScript format
+// and strings only escape what JSON requires
+// Duplicate keys, invalid UTF-8 and numbers out of the float64 range are rejected
+func CanonicalJSON(data []byte) ([]byte, error) {
+
+	l := CreateJSONLexer(data)
+	defer ReleaseJSONLexer(l)
+
+	l.SetInvalidUTF8(JSONInvalidUTF8Reject)
+
+	out, err := appendCanonicalJSON(make([]byte, 0, len(data)), l, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.ExpectEOF(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// appendCanonicalJSON appends the canonical form of the next value of l to dst
+func appendCanonicalJSON(dst []byte, l *JSONLexer, depth int) ([]byte, error) {
+
+	l.SkipWhitespace()
+
+	switch jsonKindOf(l.Peek()) {
+	case JSONTokenObjectStart:
+		return appendCanonicalObject(dst, l, depth+1)
+	case JSONTokenArrayStart:
+		if err := l.checkDepth(depth + 1); err != nil {
+			return nil, err
+		}
+		l.Advance()
+		dst = append(dst, _BracketLeft)
+		l.SkipWhitespace()
+		if l.Peek() == _BracketRight {
+			l.Advance()
+			return append(dst, _BracketRight), nil
+		}
+		for {
+			var err error
+			if dst, err = appendCanonicalJSON(dst, l, depth+1); err != nil {
+				return nil, err
+			}
+			l.SkipWhitespace()
+			if l.Peek() == _BracketRight {
+				l.Advance()
+				return append(dst, _BracketRight), nil
+			}
+			if err := l.Expect(_CommaChar); err != nil {
+				return nil, err
+			}
+			dst = append(dst, _CommaChar)
+		}
+	case JSONTokenString:
+		s, err := l.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		return appendCanonicalString(dst, s), nil
+	case JSONTokenNumber:
+		raw, err := l.ReadNumberRaw()
+		if err != nil {
+			return nil, err
+		}
+		f, err := strconv.ParseFloat(string(raw), 64)
+		if err != nil {
+			return nil, l.syntaxErrorMsg("invalid number", err)
+		}
+		return appendCanonicalNumber(dst, f), nil
+	case JSONTokenBool:
+		b, err := l.ReadBool()
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendBool(dst, b), nil
+	case JSONTokenNull:
+		if err := l.ReadNull(); err != nil {
+			return nil, err
+		}
+		return append(dst, "null"...), nil
+	default:
+		return nil, l.syntaxError("value", l.got())
+	}
+}
+
+// appendCanonicalObject appends the object at l with its members sorted
+func appendCanonicalObject(dst []byte, l *JSONLexer, depth int) ([]byte, error) {
+
+	if err := l.checkDepth(depth); err != nil {
+		return nil, err
+	}
+
+	l.Advance()
+	l.SkipWhitespace()
+
+	var members []jsonCanonicalMember
+
+	if l.Peek() == _BraceRight {
+		l.Advance()
+	} else {
+		for {
+			key, err := l.ReadString()
+
+			if err != nil {
+				return nil, err
+			}
+
+			if err := l.Expect(_ColonChar); err != nil {
+				return nil, err
+			}
+
+			value, err := appendCanonicalJSON(nil, l, depth)
+
+			if err != nil {
+				return nil, err
+			}
+
+			members = append(members, jsonCanonicalMember{key: key, value: value})
+
+			l.SkipWhitespace()
+
+			if l.Peek() == _BraceRight {
+				l.Advance()
+				break
+			}
+
+			if err := l.Expect(_CommaChar); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return lessUTF16(members[i].key, members[j].key)
+	})
+
+	dst = append(dst, _BraceLeft)
+
+	for i, m := range members {
+
+		if i > 0 {
+			if m.key == members[i-1].key {
+				return nil, l.syntaxErrorMsg("duplicate key "+strconv.Quote(m.key), nil)
+			}
+			dst = append(dst, _CommaChar)
+		}
+
+		dst = appendCanonicalString(dst, m.key)
+		dst = append(dst, _ColonChar)
+		dst = append(dst, m.value...)
+	}
+
+	return append(dst, _BraceRight), nil
+}
+
+// appendCanonicalString appends s quoted with the minimal escaping of RFC 8785
+func appendCanonicalString(dst []byte, s string) []byte {
+
+	dst = append(dst, _QuoteChar)
+
+	for i := 0; i < len(s); i++ {
+
+		c := s[i]
+
+		switch c {
+		case _QuoteChar, '\\':
+			dst = append(dst, '\\', c)
+		case '\b':
+			dst = append(dst, '\\', 'b')
+		case '\f':
+			dst = append(dst, '\\', 'f')
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		default:
+			if c < 0x20 {
+				dst = append(dst, '\\', 'u', '0', '0', _hexDigits[c>>4], _hexDigits[c&0xf])
+			} else {
+				dst = append(dst, c)
+			}
+		}
+	}
+
+	return append(dst, _QuoteChar)
+}
+
+// appendCanonicalNumber appends f formatted like ECMAScript Number.prototype.toString
+func appendCanonicalNumber(dst []byte, f float64) []byte {
+
+	if f == 0 {
+		return append(dst, '0')
+	}
+
+	if f < 0 {
+		dst = append(dst, '-')
+		f = -f
+	}
+
+	// shortest round-trip digits d.ddd and exponent
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+
+	mark := strings.IndexByte(s, 'e')
+	exp, _ := strconv.Atoi(s[mark+1:])
+	digits := strings.Replace(s[:mark], ".", "", 1)
+
+	k := len(digits)
+	n := exp + 1 // position of the decimal point relative to the digits
+
+	switch {
+	case k <= n && n <= 21:
+		dst = append(dst, digits...)
+		for i := k; i < n; i++ {
+			dst = append(dst, '0')
+		}
+	case 0 < n && n <= 21:
+		dst = append(dst, digits[:n]...)
+		dst = append(dst, '.')
+		dst = append(dst, digits[n:]...)
+	case -6 < n && n <= 0:
+		dst = append(dst, '0', '.')
+		for i := n; i < 0; i++ {
+			dst = append(dst, '0')
+		}
+		dst = append(dst, digits...)
+	default:
+		dst = append(dst, digits[0])
+		if k > 1 {
+			dst = append(dst, '.')
+			dst = append(dst, digits[1:]...)
+		}
+		dst = append(dst, 'e')
+		if n-1 >= 0 {
+			dst = append(dst, '+')
+		}
+		dst = strconv.AppendInt(dst, int64(n-1), 10)
+	}
+
+	return dst
+}
+
+// lessUTF16 compares a and b by their UTF-16 code units
+func lessUTF16(a, b string) bool {
+
+	for a != "" && b != "" {
+
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+
+		if ra != rb {
+			return utf16Unit(ra) < utf16Unit(rb) || utf16Unit(ra) == utf16Unit(rb) && ra < rb
+		}
+
+		a, b = a[na:], b[nb:]
+	}
+
+	return len(a) < len(b)
+}
+
+// utf16Unit returns the first UTF-16 code unit of r
+func utf16Unit(r rune) rune {
+	if r >= 0x10000 {
+		high, _ := utf16.EncodeRune(r)
+		return high
+	}
+	return r
+}
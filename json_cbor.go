@@ -0,0 +1,315 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+)
+
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+
+	cborFalse     = 0xf4
+	cborTrue      = 0xf5
+	cborNull      = 0xf6
+	cborUndefined = 0xf7
+	cborFloat16   = 0xf9
+	cborFloat32   = 0xfa
+	cborFloat64   = 0xfb
+	cborBreak     = 0xff
+
+	// additional information of indefinite lengths
+	cborIndefinite = 31
+)
+
+// JSONToCBOR transcodes the JSON document data to CBOR (RFC 8949)
+// Integers that fit in int64 or uint64 are encoded exactly, other numbers as floats
+// Floats use single precision when it is lossless, containers use definite lengths
+func JSONToCBOR(data []byte) ([]byte, error) {
+	return transcodeJSON(data, cborFormat{})
+}
+
+// CBORToJSON transcodes the CBOR document data to JSON
+// Byte strings become unpadded base64url strings, tags are dropped and undefined becomes null
+// Map keys must be text strings or integers, errors wrap ErrCBORInvalid
+func CBORToJSON(data []byte) ([]byte, error) {
+	return binaryToJSON(func(w *JSONWriter) error {
+		in := &binaryInput{data: data, name: "cbor", invalid: ErrCBORInvalid}
+		if err := readCBOR(in, w, 0); err != nil {
+			return err
+		}
+		return in.expectEnd()
+	})
+}
+
+// cborFormat appends values in CBOR
+type cborFormat struct{}
+
+func (cborFormat) appendNull(dst []byte) []byte {
+	return append(dst, cborNull)
+}
+
+func (cborFormat) appendBool(dst []byte, v bool) []byte {
+	if v {
+		return append(dst, cborTrue)
+	}
+	return append(dst, cborFalse)
+}
+
+func (cborFormat) appendInt(dst []byte, v int64) []byte {
+	if v < 0 {
+		return appendCBORHead(dst, cborNegint, uint64(^v))
+	}
+	return appendCBORHead(dst, cborUint, uint64(v))
+}
+
+func (cborFormat) appendUint(dst []byte, v uint64) []byte {
+	return appendCBORHead(dst, cborUint, v)
+}
+
+func (cborFormat) appendFloat(dst []byte, v float64) []byte {
+	if isFloat32(v) {
+		return appendUint32(append(dst, cborFloat32), math.Float32bits(float32(v)))
+	}
+	return appendUint64(append(dst, cborFloat64), math.Float64bits(v))
+}
+
+func (cborFormat) appendString(dst []byte, v string) []byte {
+	return append(appendCBORHead(dst, cborText, uint64(len(v))), v...)
+}
+
+func (cborFormat) appendArray(dst []byte, n uint64) []byte {
+	return appendCBORHead(dst, cborArray, n)
+}
+
+func (cborFormat) appendMap(dst []byte, n uint64) []byte {
+	return appendCBORHead(dst, cborMap, n)
+}
+
+// appendCBORHead appends the initial byte of major type major with argument v in the shortest form
+func appendCBORHead(dst []byte, major byte, v uint64) []byte {
+
+	major <<= 5
+
+	switch {
+	case v < 24:
+		return append(dst, major|byte(v))
+	case v <= math.MaxUint8:
+		return append(dst, major|24, byte(v))
+	case v <= math.MaxUint16:
+		return appendUint16(append(dst, major|25), uint16(v))
+	case v <= math.MaxUint32:
+		return appendUint32(append(dst, major|26), uint32(v))
+	default:
+		return appendUint64(append(dst, major|27), v)
+	}
+}
+
+// readCBORHead reads an initial byte and its argument
+// indefinite is set for additional information 31, which has no argument
+func readCBORHead(in *binaryInput) (major byte, info byte, arg uint64, indefinite bool, err error) {
+
+	c, err := in.byte()
+
+	if err != nil {
+		return 0, 0, 0, false, err
+	}
+
+	major, info = c>>5, c&0x1f
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info <= 27:
+		arg, err = in.uint(1 << (info - 24))
+		return major, info, arg, false, err
+	case info == cborIndefinite && major != cborUint && major != cborNegint && major != cborTag:
+		return major, info, 0, true, nil
+	default:
+		in.pos--
+		return 0, 0, 0, false, in.errorf("invalid initial byte 0x%02x", c)
+	}
+}
+
+// readCBOR reads one data item from in and writes it to w
+func readCBOR(in *binaryInput, w *JSONWriter, depth int) error {
+
+	start := in.pos
+
+	major, info, arg, indefinite, err := readCBORHead(in)
+
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case cborUint:
+		return w.WriteUint64(arg)
+	case cborNegint:
+		if arg <= math.MaxInt64 {
+			return w.WriteInt64(-1 - int64(arg))
+		}
+		return w.WriteRaw([]byte(negativeUintString(arg)))
+	case cborBytes, cborText:
+		s, err := readCBORString(in, major, arg, indefinite)
+		if err != nil {
+			return err
+		}
+		if major == cborBytes {
+			return writeBinaryBytes(w, s)
+		}
+		return w.WriteString(string(s))
+	case cborArray, cborMap:
+		if err := in.checkDepth(depth + 1); err != nil {
+			return err
+		}
+		if major == cborArray {
+			err = w.BeginArray()
+		} else {
+			err = w.BeginObject()
+		}
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && in.pos < len(in.data) && in.data[in.pos] == cborBreak {
+				in.pos++
+				break
+			}
+			if major == cborMap {
+				key, err := readCBORKey(in)
+				if err != nil {
+					return err
+				}
+				if err := w.Key(key); err != nil {
+					return err
+				}
+			}
+			if err := readCBOR(in, w, depth+1); err != nil {
+				return err
+			}
+		}
+		if major == cborArray {
+			return w.EndArray()
+		}
+		return w.EndObject()
+	case cborTag:
+		if err := in.checkDepth(depth + 1); err != nil {
+			return err
+		}
+		return readCBOR(in, w, depth+1)
+	}
+
+	switch info {
+	case cborFalse & 0x1f:
+		return w.WriteBool(false)
+	case cborTrue & 0x1f:
+		return w.WriteBool(true)
+	case cborNull & 0x1f, cborUndefined & 0x1f:
+		return w.WriteNull()
+	case cborFloat16 & 0x1f:
+		return in.writeFloat(w, float16ToFloat64(uint16(arg)), start)
+	case cborFloat32 & 0x1f:
+		return in.writeFloat(w, float64(math.Float32frombits(uint32(arg))), start)
+	case cborFloat64 & 0x1f:
+		return in.writeFloat(w, math.Float64frombits(arg), start)
+	}
+
+	in.pos = start
+
+	if indefinite {
+		return in.errorf("unexpected break")
+	}
+
+	return in.errorf("unsupported simple value %d", arg)
+}
+
+// readCBORString reads the content of a byte or text string, joining indefinite-length chunks
+func readCBORString(in *binaryInput, major byte, arg uint64, indefinite bool) ([]byte, error) {
+
+	if !indefinite {
+		return in.bytes(arg)
+	}
+
+	var s []byte
+
+	for {
+		if in.pos < len(in.data) && in.data[in.pos] == cborBreak {
+			in.pos++
+			return s, nil
+		}
+
+		chunkMajor, _, n, chunkIndefinite, err := readCBORHead(in)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if chunkMajor != major || chunkIndefinite {
+			return nil, in.errorf("invalid string chunk")
+		}
+
+		chunk, err := in.bytes(n)
+
+		if err != nil {
+			return nil, err
+		}
+
+		s = append(s, chunk...)
+	}
+}
+
+// readCBORKey reads a map key, integers are converted to their decimal text
+func readCBORKey(in *binaryInput) (string, error) {
+
+	start := in.pos
+
+	major, _, arg, indefinite, err := readCBORHead(in)
+
+	if err != nil {
+		return "", err
+	}
+
+	switch major {
+	case cborText:
+		s, err := readCBORString(in, major, arg, indefinite)
+		return string(s), err
+	case cborUint:
+		return strconv.FormatUint(arg, 10), nil
+	case cborNegint:
+		return negativeUintString(arg), nil
+	default:
+		in.pos = start
+		return "", in.errorf("unsupported map key of major type %d", major)
+	}
+}
+
+// float16ToFloat64 converts an IEEE 754 half-precision number
+func float16ToFloat64(h uint16) float64 {
+
+	sign := 1.0
+
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	default:
+		return sign * math.Ldexp(mant+1024, exp-25)
+	}
+}
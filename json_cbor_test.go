@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONToCBOR(t *testing.T) {
+	// examples from RFC 8949 appendix A, floats use single precision when lossless
+	tests := []struct {
+		json string
+		cbor string
+	}{
+		{json: `0`, cbor: "00"},
+		{json: `23`, cbor: "17"},
+		{json: `24`, cbor: "1818"},
+		{json: `1000`, cbor: "1903e8"},
+		{json: `1000000`, cbor: "1a000f4240"},
+		{json: `1000000000000`, cbor: "1b000000e8d4a51000"},
+		{json: `18446744073709551615`, cbor: "1bffffffffffffffff"},
+		{json: `-1`, cbor: "20"},
+		{json: `-1000`, cbor: "3903e7"},
+		{json: `-9223372036854775808`, cbor: "3b7fffffffffffffff"},
+		{json: `1.1`, cbor: "fb3ff199999999999a"},
+		{json: `1.5`, cbor: "fa3fc00000"},
+		{json: `100000.0`, cbor: "fa47c35000"},
+		{json: `1.0e+300`, cbor: "fb7e37e43c8800759c"},
+		{json: `-4.1`, cbor: "fbc010666666666666"},
+		{json: `false`, cbor: "f4"},
+		{json: `true`, cbor: "f5"},
+		{json: `null`, cbor: "f6"},
+		{json: `""`, cbor: "60"},
+		{json: `"IETF"`, cbor: "6449455446"},
+		{json: `"ü"`, cbor: "62c3bc"},
+		{json: `[]`, cbor: "80"},
+		{json: `[1, [2, 3], [4, 5]]`, cbor: "8301820203820405"},
+		{json: `[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21,22,23,24,25]`, cbor: "98190102030405060708090a0b0c0d0e0f101112131415161718181819"},
+		{json: `{}`, cbor: "a0"},
+		{json: `{"a": 1, "b": [2, 3]}`, cbor: "a26161016162820203"},
+	}
+
+	for _, tt := range tests {
+		got, err := JSONToCBOR([]byte(tt.json))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.json, err)
+		}
+		if hex.EncodeToString(got) != tt.cbor {
+			t.Errorf("%s: expected %s, got %x", tt.json, tt.cbor, got)
+		}
+	}
+
+	for _, bad := range []string{`[1,]`, `1 2`, `1e400`} {
+		if _, err := JSONToCBOR([]byte(bad)); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestCBORToJSON(t *testing.T) {
+	tests := []struct {
+		cbor string
+		json string
+	}{
+		{cbor: "3bffffffffffffffff", json: `-18446744073709551616`},
+		{cbor: "f93e00", json: `1.5`},
+		{cbor: "5f42010243030405ff", json: `"AQIDBAU"`},
+		{cbor: "7f657374726561646d696e67ff", json: `"streaming"`},
+		{cbor: "9fff", json: `[]`},
+		{cbor: "bf61610161629f0203ffff", json: `{"a":1,"b":[2,3]}`},
+		{cbor: "c074323031332d30332d32315432303a30343a30305a", json: `"2013-03-21T20:04:00Z"`},
+		{cbor: "a201020304", json: `{"1":2,"3":4}`},
+		{cbor: "f7", json: `null`},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.cbor)
+		got, err := CBORToJSON(data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.cbor, err)
+		}
+		if string(got) != tt.json {
+			t.Errorf("%s: expected %s, got %s", tt.cbor, tt.json, got)
+		}
+	}
+
+	for _, bad := range []string{"", "1903", "ff", "0000", "1c", "a18001", "f97c00", "f97e00", "fa7f800000", "fbfff0000000000000", "5f6161ff", "f0", "8201"} {
+		data, _ := hex.DecodeString(bad)
+		if _, err := CBORToJSON(data); !errors.Is(err, ErrCBORInvalid) {
+			t.Errorf("%s: expected ErrCBORInvalid, got %v", bad, err)
+		}
+	}
+
+	deep, _ := hex.DecodeString(strings.Repeat("81", jsonDefaultMaxDepth+1) + "00")
+	if _, err := CBORToJSON(deep); !errors.Is(err, ErrJSONTooDeep) {
+		t.Errorf("expected ErrJSONTooDeep, got %v", err)
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	doc := `{"i":-9223372036854775808,"u":18446744073709551615,"f":0.1,"s":"x\"y","n":null,"a":[true,false,1.5,{}]}`
+
+	data, err := JSONToCBOR([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := CBORToJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != doc {
+		t.Errorf("expected %s, got %s", doc, got)
+	}
+}
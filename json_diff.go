@@ -0,0 +1,436 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// JSONChange is one difference between two JSON documents
+// Op is "add", "remove" or "replace" as in RFC 6902, Path is a JSON Pointer
+// Old is nil for add and New is nil for remove, both refer to the compared documents
+type JSONChange struct {
+	Op   string
+	Path string
+	Old  []byte
+	New  []byte
+}
+
+// JSONChanges is the list of changes turning one document into another
+// Paths are valid when the changes are applied in order, like a JSON Patch
+type JSONChanges []JSONChange
+
+// JSONDiff returns the changes turning the JSON document a into b
+// Objects are compared member by member and arrays index by index
+func JSONDiff(a, b []byte) (JSONChanges, error) {
+	return JSONDiffByKey(a, b, "")
+}
+
+// JSONDiffByKey is like JSONDiff but matches array elements by the value of their key member
+// Arrays are matched this way when all their elements are objects with a unique string or number key
+// Other arrays are compared index by index, an empty key always compares by index
+func JSONDiffByKey(a, b []byte, key string) (JSONChanges, error) {
+
+	x, err := parseJSONDiffDocument(a)
+
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := parseJSONDiffDocument(b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	d := &jsonDiff{key: key}
+
+	d.diff(nil, x, y)
+
+	return d.changes, nil
+}
+
+// Patch converts the changes to an RFC 6902 JSON Patch
+// Values are decoded with numbers kept as json.Number
+func (c JSONChanges) Patch() (JSONPatch, error) {
+
+	patch := make(JSONPatch, len(c))
+
+	for i, change := range c {
+
+		patch[i] = JSONPatchOperation{Op: change.Op, Path: change.Path}
+
+		if change.Op == "remove" {
+			continue
+		}
+
+		v, err := decodeJSONValue(change.New)
+
+		if err != nil {
+			return nil, err
+		}
+
+		patch[i].Value = v
+	}
+
+	return patch, nil
+}
+
+// jsonDiffNode is a parsed value with its raw bytes
+type jsonDiffNode struct {
+	kind    JSONTokenKind
+	raw     []byte
+	str     string // decoded value of a string
+	keys    []string
+	members map[string]*jsonDiffNode
+	elems   []*jsonDiffNode
+}
+
+// parseJSONDiffDocument parses data into a tree of nodes
+func parseJSONDiffDocument(data []byte) (*jsonDiffNode, error) {
+
+	l := CreateJSONLexer(data)
+	defer ReleaseJSONLexer(l)
+
+	n, err := parseJSONDiffNode(l, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.ExpectEOF(); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// parseJSONDiffNode parses the next value of l
+func parseJSONDiffNode(l *JSONLexer, depth int) (*jsonDiffNode, error) {
+
+	l.SkipWhitespace()
+
+	start := l.pos
+	n := &jsonDiffNode{kind: jsonKindOf(l.Peek())}
+
+	switch n.kind {
+	case JSONTokenObjectStart:
+		if err := l.checkDepth(depth + 1); err != nil {
+			return nil, err
+		}
+		l.Advance()
+		l.SkipWhitespace()
+		n.members = map[string]*jsonDiffNode{}
+		if l.Peek() == _BraceRight {
+			l.Advance()
+			break
+		}
+		for {
+			key, err := l.ReadString()
+			if err != nil {
+				return nil, err
+			}
+			if err := l.Expect(_ColonChar); err != nil {
+				return nil, err
+			}
+			value, err := parseJSONDiffNode(l, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			// the last of duplicate keys wins, as in encoding/json
+			if _, ok := n.members[key]; !ok {
+				n.keys = append(n.keys, key)
+			}
+			n.members[key] = value
+			l.SkipWhitespace()
+			if l.Peek() == _BraceRight {
+				l.Advance()
+				break
+			}
+			if err := l.Expect(_CommaChar); err != nil {
+				return nil, err
+			}
+		}
+	case JSONTokenArrayStart:
+		if err := l.checkDepth(depth + 1); err != nil {
+			return nil, err
+		}
+		l.Advance()
+		l.SkipWhitespace()
+		if l.Peek() == _BracketRight {
+			l.Advance()
+			break
+		}
+		for {
+			value, err := parseJSONDiffNode(l, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.elems = append(n.elems, value)
+			l.SkipWhitespace()
+			if l.Peek() == _BracketRight {
+				l.Advance()
+				break
+			}
+			if err := l.Expect(_CommaChar); err != nil {
+				return nil, err
+			}
+		}
+	case JSONTokenString:
+		s, err := l.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		n.str = s
+	case JSONTokenNumber:
+		if _, err := l.ReadNumberRaw(); err != nil {
+			return nil, err
+		}
+	case JSONTokenBool:
+		if _, err := l.ReadBool(); err != nil {
+			return nil, err
+		}
+	case JSONTokenNull:
+		if err := l.ReadNull(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, l.syntaxError("value", l.got())
+	}
+
+	n.raw = l.data[start:l.pos]
+
+	return n, nil
+}
+
+// equal reports whether n and m are the same scalar value
+// numbers are compared by value, so 1.0 equals 1
+func (n *jsonDiffNode) equal(m *jsonDiffNode) bool {
+
+	if n.kind != m.kind {
+		return false
+	}
+
+	switch n.kind {
+	case JSONTokenString:
+		return n.str == m.str
+	case JSONTokenNumber:
+		return jsonEqual(json.Number(n.raw), json.Number(m.raw))
+	default:
+		return bytes.Equal(n.raw, m.raw)
+	}
+}
+
+// id returns the identity of a scalar for matching array elements
+func (n *jsonDiffNode) id() (string, bool) {
+
+	switch n.kind {
+	case JSONTokenString:
+		return "s" + n.str, true
+	case JSONTokenNumber:
+		r, ok := jsonRat(json.Number(n.raw))
+		if !ok {
+			return "", false
+		}
+		return "n" + r.RatString(), true
+	default:
+		return "", false
+	}
+}
+
+// jsonDiff collects the changes between two trees
+type jsonDiff struct {
+	key     string
+	changes JSONChanges
+}
+
+// add records a change at the path of tokens
+func (d *jsonDiff) add(op string, path []string, old, value *jsonDiffNode) {
+
+	change := JSONChange{Op: op, Path: JoinJSONPointer(path...)}
+
+	if old != nil {
+		change.Old = old.raw
+	}
+
+	if value != nil {
+		change.New = value.raw
+	}
+
+	d.changes = append(d.changes, change)
+}
+
+// diff records the changes turning x into y at path
+func (d *jsonDiff) diff(path []string, x, y *jsonDiffNode) {
+
+	switch {
+	case x.kind != y.kind:
+		d.add("replace", path, x, y)
+	case x.kind == JSONTokenObjectStart:
+		d.diffObject(path, x, y)
+	case x.kind == JSONTokenArrayStart:
+		if xs, ys, ok := d.arrayIDs(x, y); ok {
+			d.diffArrayByKey(path, x, y, xs, ys)
+		} else {
+			d.diffArray(path, x, y)
+		}
+	case !x.equal(y):
+		d.add("replace", path, x, y)
+	}
+}
+
+// diffObject records removed members, then changed ones, then added ones
+func (d *jsonDiff) diffObject(path []string, x, y *jsonDiffNode) {
+
+	for _, k := range x.keys {
+		if _, ok := y.members[k]; !ok {
+			d.add("remove", jsonDiffPath(path, k), x.members[k], nil)
+		}
+	}
+
+	for _, k := range x.keys {
+		if w, ok := y.members[k]; ok {
+			d.diff(jsonDiffPath(path, k), x.members[k], w)
+		}
+	}
+
+	for _, k := range y.keys {
+		if _, ok := x.members[k]; !ok {
+			d.add("add", jsonDiffPath(path, k), nil, y.members[k])
+		}
+	}
+}
+
+// diffArray compares the elements of x and y at the same index
+// extra elements of x are removed from the end so the indexes stay valid
+func (d *jsonDiff) diffArray(path []string, x, y *jsonDiffNode) {
+
+	common := len(x.elems)
+
+	if len(y.elems) < common {
+		common = len(y.elems)
+	}
+
+	for i := 0; i < common; i++ {
+		d.diff(jsonDiffPath(path, strconv.Itoa(i)), x.elems[i], y.elems[i])
+	}
+
+	for i := len(x.elems) - 1; i >= common; i-- {
+		d.add("remove", jsonDiffPath(path, strconv.Itoa(i)), x.elems[i], nil)
+	}
+
+	for i := common; i < len(y.elems); i++ {
+		d.add("add", jsonDiffPath(path, strconv.Itoa(i)), nil, y.elems[i])
+	}
+}
+
+// diffArrayByKey matches the elements of x and y by their ids
+// Elements missing from y are removed, then y is built front to back:
+// matched elements in place are compared, new ones are added
+// and matched elements out of place are removed and added again
+func (d *jsonDiff) diffArrayByKey(path []string, x, y *jsonDiffNode, xs, ys []string) {
+
+	inY := make(map[string]bool, len(ys))
+
+	for _, id := range ys {
+		inY[id] = true
+	}
+
+	byID := make(map[string]*jsonDiffNode, len(xs))
+
+	for i := len(xs) - 1; i >= 0; i-- {
+		if inY[xs[i]] {
+			byID[xs[i]] = x.elems[i]
+		} else {
+			d.add("remove", jsonDiffPath(path, strconv.Itoa(i)), x.elems[i], nil)
+		}
+	}
+
+	// ids of the array as the changes so far leave it
+	current := make([]string, 0, len(xs))
+
+	for _, id := range xs {
+		if inY[id] {
+			current = append(current, id)
+		}
+	}
+
+	for j, id := range ys {
+
+		p := jsonDiffPath(path, strconv.Itoa(j))
+		old, matched := byID[id]
+
+		switch {
+		case j < len(current) && current[j] == id:
+			d.diff(p, old, y.elems[j])
+			continue
+		case matched:
+			k := j + 1
+			for current[k] != id {
+				k++
+			}
+			d.add("remove", jsonDiffPath(path, strconv.Itoa(k)), old, nil)
+			current = append(current[:k], current[k+1:]...)
+		}
+
+		d.add("add", p, nil, y.elems[j])
+
+		current = append(current, "")
+		copy(current[j+1:], current[j:])
+		current[j] = id
+	}
+}
+
+// arrayIDs returns the ids of the elements of x and y when they can be matched by key
+func (d *jsonDiff) arrayIDs(x, y *jsonDiffNode) ([]string, []string, bool) {
+
+	if d.key == "" {
+		return nil, nil, false
+	}
+
+	xs, ok := d.elementIDs(x)
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	ys, ok := d.elementIDs(y)
+
+	return xs, ys, ok
+}
+
+// elementIDs returns the unique ids of the elements of an array
+func (d *jsonDiff) elementIDs(n *jsonDiffNode) ([]string, bool) {
+
+	ids := make([]string, len(n.elems))
+	seen := make(map[string]bool, len(n.elems))
+
+	for i, e := range n.elems {
+
+		if e.kind != JSONTokenObjectStart {
+			return nil, false
+		}
+
+		k, ok := e.members[d.key]
+
+		if !ok {
+			return nil, false
+		}
+
+		id, ok := k.id()
+
+		if !ok || seen[id] {
+			return nil, false
+		}
+
+		ids[i] = id
+		seen[id] = true
+	}
+
+	return ids, true
+}
+
+// jsonDiffPath returns path extended by token without sharing its backing array
+func jsonDiffPath(path []string, token string) []string {
+	return append(path[:len(path):len(path)], token)
+}
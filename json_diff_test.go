@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		key  string
+		want []string
+	}{
+		{name: "equal", a: `{"a": [1, 2.0, "x"], "b": null}`, b: `{"b":null,"a":[1.0,2,"x"]}`},
+		{name: "members", a: `{"a": 1, "b": {"c": true}}`, b: `{"b": {"c": false}, "d/e": [1]}`, want: []string{
+			`remove /a 1 `,
+			`replace /b/c true false`,
+			`add /d~1e  [1]`,
+		}},
+		{name: "root", a: `[1]`, b: `{"a": 1}`, want: []string{`replace  [1] {"a": 1}`}},
+		{name: "shrink", a: `[1, 2, 3, 4]`, b: `[0, 2]`, want: []string{
+			`replace /0 1 0`,
+			`remove /3 4 `,
+			`remove /2 3 `,
+		}},
+		{name: "grow", a: `["a"]`, b: `["a", "b", "c"]`, want: []string{`add /1  "b"`, `add /2  "c"`}},
+		{name: "by index", a: `[{"id": 1, "v": "a"}, {"id": 2, "v": "b"}]`, b: `[{"id": 2, "v": "b"}]`, want: []string{
+			`replace /0/id 1 2`,
+			`replace /0/v "a" "b"`,
+			`remove /1 {"id": 2, "v": "b"} `,
+		}},
+		{name: "by key", key: "id", a: `[{"id": 1, "v": "a"}, {"id": 2, "v": "b"}, {"id": 3}]`, b: `[{"id": 2, "v": "c"}, {"id": 4}, {"id": 3}]`, want: []string{
+			`remove /0 {"id": 1, "v": "a"} `,
+			`replace /0/v "b" "c"`,
+			`add /1  {"id": 4}`,
+		}},
+		{name: "by key moved", key: "id", a: `[{"id": "x"}, {"id": "y"}]`, b: `[{"id": "y"}, {"id": "x", "n": 1}]`, want: []string{
+			`remove /1 {"id": "y"} `,
+			`add /0  {"id": "y"}`,
+			`add /1/n  1`,
+		}},
+		{name: "by key fallback", key: "id", a: `[{"id": 1}, {"id": 1}]`, b: `[{"id": 1}]`, want: []string{`remove /1 {"id": 1} `}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := JSONDiffByKey([]byte(tt.a), []byte(tt.b), tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, c := range changes {
+				got = append(got, c.Op+" "+c.Path+" "+string(c.Old)+" "+string(c.New))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+
+			// the changes as a patch turn a into b
+			patch, err := changes.Patch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, _ := decodeJSONValue([]byte(tt.a))
+			b, _ := decodeJSONValue([]byte(tt.b))
+			result, err := patch.Apply(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(result, b) {
+				t.Errorf("patch result %v, expected %v", result, b)
+			}
+		})
+	}
+
+	for _, bad := range [][2]string{{`{"a": }`, `{}`}, {`{}`, `[1] 2`}} {
+		if _, err := JSONDiff([]byte(bad[0]), []byte(bad[1])); err == nil {
+			t.Errorf("%s %s: expected error", bad[0], bad[1])
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// longest part of a line shown on each side of the error
+	jsonSnippetContext = 60
+)
+
+// JSONSyntaxError is malformed JSON input found at a position
+// Line and Column are 1-based, they are 0 when only the offset is known
+type JSONSyntaxError struct {
+	Offset   int    // byte offset of the error in the input
+	Line     int    // line of the error
+	Column   int    // column of the error, counted in characters
+	Expected string // what the input should have had, such as "':'" or "value"
+	Got      string // what the input had instead, such as "'}'" or "EOF"
+	Msg      string // description of the error when Expected and Got do not say enough
+	Err      error  // underlying error, such as io.EOF
+}
+
+func (e *JSONSyntaxError) Error() string {
+
+	var sb strings.Builder
+
+	if e.Line > 0 {
+		fmt.Fprintf(&sb, "line %d, column %d: ", e.Line, e.Column)
+	} else {
+		fmt.Fprintf(&sb, "offset %d: ", e.Offset)
+	}
+
+	switch {
+	case e.Msg != "":
+		sb.WriteString(e.Msg)
+	case e.Expected != "":
+		sb.WriteString("expected " + e.Expected + ", got " + e.Got)
+	case e.Err != nil:
+		sb.WriteString(e.Err.Error())
+		return sb.String()
+	default:
+		sb.WriteString("syntax error")
+	}
+
+	// io.EOF adds nothing to "got EOF"
+	if e.Err != nil && !(e.Err == io.EOF && e.Got == "EOF") {
+		sb.WriteString(": " + e.Err.Error())
+	}
+
+	return sb.String()
+}
+
+func (e *JSONSyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Snippet renders the line of data holding the error with a caret under the offending character
+// data must be the input the error was found in
+//
+//	3 |   "port": 80a,
+//	  |             ^
+func (e *JSONSyntaxError) Snippet(data []byte) string {
+
+	offset := e.Offset
+
+	if offset > len(data) {
+		offset = len(data)
+	}
+
+	start := offset
+	for start > 0 && data[start-1] != '\n' {
+		start--
+	}
+
+	end := offset
+	for end < len(data) && data[end] != '\n' {
+		end++
+	}
+
+	line := e.Line
+	if line == 0 {
+		line = 1 + strings.Count(string(data[:start]), "\n")
+	}
+
+	// keep long lines around the error, cutting on character boundaries
+	prefix, suffix := "", ""
+
+	if offset-start > jsonSnippetContext {
+		start = offset - jsonSnippetContext
+		for start < offset && !utf8.RuneStart(data[start]) {
+			start++
+		}
+		prefix = "..."
+	}
+
+	if end-offset > jsonSnippetContext {
+		end = offset + jsonSnippetContext
+		for end > offset && !utf8.RuneStart(data[end]) {
+			end--
+		}
+		suffix = "..."
+	}
+
+	text := strings.TrimSuffix(string(data[start:end]), "\r")
+
+	var caret strings.Builder
+
+	caret.WriteString(strings.Repeat(" ", len(prefix)))
+
+	// tabs are kept so the caret lines up in a terminal
+	for _, c := range string(data[start:offset]) {
+		if c == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+
+	caret.WriteByte('^')
+
+	number := strconv.Itoa(line)
+	margin := strings.Repeat(" ", len(number))
+
+	return fmt.Sprintf("%s | %s%s%s\n%s | %s", number, prefix, text, suffix, margin, caret.String())
+}
+
+// jsonQuoteByte describes the input byte c for an error
+func jsonQuoteByte(c byte) string {
+	if c < 0x20 || c >= utf8.RuneSelf {
+		return fmt.Sprintf("byte 0x%02x", c)
+	}
+	return "'" + string(c) + "'"
+}
+
+// syntaxError returns a syntax error at the current position of the lexer
+func (l *JSONLexer) syntaxError(expected, got string) *JSONSyntaxError {
+	return &JSONSyntaxError{Offset: l.base + l.pos, Line: l.line, Column: l.column, Expected: expected, Got: got}
+}
+
+// syntaxErrorMsg returns a syntax error described by msg at the current position of the lexer
+func (l *JSONLexer) syntaxErrorMsg(msg string, err error) *JSONSyntaxError {
+	return &JSONSyntaxError{Offset: l.base + l.pos, Line: l.line, Column: l.column, Msg: msg, Err: err}
+}
+
+// got describes the byte at the current position of the lexer
+func (l *JSONLexer) got() string {
+	if l.pos >= l.len {
+		return "EOF"
+	}
+	return jsonQuoteByte(l.data[l.pos])
+}
+
+// syntaxError returns a syntax error at the current position of the reader
+func (r *JSONReader) syntaxError(expected, got string) *JSONSyntaxError {
+	return &JSONSyntaxError{Offset: r.offset + r.pos, Line: r.line, Column: r.column, Expected: expected, Got: got}
+}
+
+// syntaxErrorMsg returns a syntax error described by msg at the current position of the reader
+func (r *JSONReader) syntaxErrorMsg(msg string, err error) *JSONSyntaxError {
+	return &JSONSyntaxError{Offset: r.offset + r.pos, Line: r.line, Column: r.column, Msg: msg, Err: err}
+}
+
+// jsonTextError returns a syntax error at offset in the input of a json_text function
+// the streaming functions do not track lines, so only the offset is known
+func jsonTextError(offset int, expected, got string, err error) *JSONSyntaxError {
+	return &JSONSyntaxError{Offset: offset, Expected: expected, Got: got, Err: err}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestJSONSyntaxError(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		offset   int
+		line     int
+		column   int
+		expected string
+		got      string
+	}{
+		{name: "colon", input: "{\n  \"a\" 1}", offset: 8, line: 2, column: 7, expected: "':'", got: "'1'"},
+		{name: "value", input: "[1,\n ]", offset: 5, line: 2, column: 2, expected: "value", got: "']'"},
+		{name: "escape", input: `"a\x"`, offset: 3, line: 1, column: 4, expected: "escape character", got: "'x'"},
+		{name: "exponent", input: `[1e]`, offset: 3, line: 1, column: 4, expected: "digit in exponent", got: "']'"},
+		{name: "eof", input: `{"a": 1`, offset: 7, line: 1, column: 8, expected: "',' or '}'", got: "EOF"},
+	}
+
+	check := func(t *testing.T, err error, offset, line, column int, expected, got string) {
+		var syntaxErr *JSONSyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatalf("expected *JSONSyntaxError, got %v", err)
+		}
+		if syntaxErr.Offset != offset || syntaxErr.Line != line || syntaxErr.Column != column {
+			t.Errorf("expected %d:%d:%d, got %d:%d:%d", offset, line, column, syntaxErr.Offset, syntaxErr.Line, syntaxErr.Column)
+		}
+		if syntaxErr.Expected != expected || syntaxErr.Got != got {
+			t.Errorf("expected %s/%s, got %s/%s", expected, got, syntaxErr.Expected, syntaxErr.Got)
+		}
+	}
+
+	drain := func(next func() (JSONToken, error)) error {
+		for {
+			if _, err := next(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := CreateJSONLexer([]byte(tt.input))
+			defer ReleaseJSONLexer(l)
+			check(t, drain(l.Next), tt.offset, tt.line, tt.column, tt.expected, tt.got)
+
+			// a small reader buffer moves the input several times before the error
+			r := CreateJSONReader(iotest.OneByteReader(strings.NewReader(tt.input)))
+			defer ReleaseJSONReader(r)
+			check(t, drain(r.Next), tt.offset, tt.line, tt.column, tt.expected, tt.got)
+		})
+	}
+
+	_, _, err := SkipValue(strings.NewReader(`[tru`), make([]byte, 16), 0, 0)
+	var syntaxErr *JSONSyntaxError
+	if !errors.As(err, &syntaxErr) || !errors.Is(err, io.EOF) {
+		t.Errorf("expected *JSONSyntaxError wrapping io.EOF, got %v", err)
+	}
+
+	// offsets of the streaming functions count the bytes dropped from a small buffer
+	for input, offset := range map[string]int{`{"key": [1, 2, "a\q"]}`: 18, `{"key": [1, 2, nul]}`: 15, `{"key": [1, 2, -x]}`: 16} {
+		_, _, err := SkipValue(strings.NewReader(input), make([]byte, 8), 0, 0)
+		if !errors.As(err, &syntaxErr) || syntaxErr.Offset != offset {
+			t.Errorf("%s: expected *JSONSyntaxError at %d, got %v", input, offset, err)
+		}
+	}
+}
+
+func TestJSONSyntaxErrorSnippet(t *testing.T) {
+	data := []byte("{\n\t\"port\": 80a,\n\t\"host\": \"x\"\n}")
+
+	l := CreateJSONLexer(data)
+	defer ReleaseJSONLexer(l)
+
+	err := l.SkipValue()
+
+	var syntaxErr *JSONSyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected *JSONSyntaxError, got %v", err)
+	}
+
+	want := "2 | \t\"port\": 80a,\n  | \t          ^"
+	if got := syntaxErr.Snippet(data); got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+
+	long := []byte(`{"a": "` + strings.Repeat("x", 100) + `" "b": 1}`)
+	err = CreateJSONLexer(long).SkipValue()
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected *JSONSyntaxError, got %v", err)
+	}
+	lines := strings.Split(syntaxErr.Snippet(long), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "1 | ...") || strings.Index(lines[1], "^") != strings.Index(lines[0], `" "b"`)+2 {
+		t.Errorf("unexpected snippet:\n%s", strings.Join(lines, "\n"))
+	}
+}
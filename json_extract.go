@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// JSONExtractFunc receives a value matched by JSONExtractor
+// path is the pattern that matched, raw the undecoded value and kind its type
+// raw is only valid during the call, returning ErrJSONStop ends the scan without error
+type JSONExtractFunc func(path string, raw []byte, kind JSONTokenKind) error
+
+// JSONExtractor pulls a set of dotted paths out of a document in a single pass
+// A path such as "user.id" names object keys, "#" matches every element of an array, as in "items.#.price"
+// Subtrees that no path leads into are skipped without decoding
+type JSONExtractor struct {
+	root *jsonPathNode
+}
+
+// jsonPathNode is a node in the tree of extracted paths
+type jsonPathNode struct {
+	children map[string]*jsonPathNode
+	elements *jsonPathNode
+	paths    []string
+}
+
+// NewJSONExtractor compiles paths into an extractor that is safe for concurrent use
+func NewJSONExtractor(paths ...string) *JSONExtractor {
+
+	root := &jsonPathNode{}
+
+	for _, path := range paths {
+
+		node := root
+
+		for _, segment := range strings.Split(path, ".") {
+
+			if segment == "#" {
+				if node.elements == nil {
+					node.elements = &jsonPathNode{}
+				}
+				node = node.elements
+				continue
+			}
+
+			if node.children == nil {
+				node.children = map[string]*jsonPathNode{}
+			}
+
+			child, ok := node.children[segment]
+
+			if !ok {
+				child = &jsonPathNode{}
+				node.children[segment] = child
+			}
+
+			node = child
+		}
+
+		node.paths = append(node.paths, path)
+	}
+
+	return &JSONExtractor{root: root}
+}
+
+// Extract scans data once and calls fn for every match
+func (e *JSONExtractor) Extract(data []byte, fn JSONExtractFunc) error {
+	l := CreateJSONLexer(data)
+	defer ReleaseJSONLexer(l)
+
+	return jsonStopped(e.walk(l, e.root, fn))
+}
+
+// ExtractReader scans the document read from r once and calls fn for every match
+// Only the matched values and the token being read are buffered
+func (e *JSONExtractor) ExtractReader(r io.Reader, fn JSONExtractFunc) error {
+	reader := CreateJSONReader(r)
+	defer ReleaseJSONReader(reader)
+
+	return jsonStopped(e.walk(reader, e.root, fn))
+}
+
+// JSONExtract scans data once and calls fn for every value matching one of paths
+func JSONExtract(data []byte, paths []string, fn JSONExtractFunc) error {
+	return NewJSONExtractor(paths...).Extract(data, fn)
+}
+
+// walk matches the value at s against node
+func (e *JSONExtractor) walk(s JSONScanner, node *jsonPathNode, fn JSONExtractFunc) error {
+
+	s.SkipWhitespace()
+
+	if len(node.paths) > 0 {
+
+		kind := jsonKindOf(s.Peek())
+
+		raw, err := s.ReadRawValue()
+
+		if err != nil {
+			return err
+		}
+
+		for _, path := range node.paths {
+			if err := fn(path, raw, kind); err != nil {
+				return err
+			}
+		}
+
+		if node.children == nil && node.elements == nil {
+			return nil
+		}
+
+		// longer paths continue inside the matched value
+		l := CreateJSONLexer(raw)
+		defer ReleaseJSONLexer(l)
+
+		return e.walk(l, &jsonPathNode{children: node.children, elements: node.elements}, fn)
+	}
+
+	switch s.Peek() {
+	case _BraceLeft:
+		if node.children == nil {
+			return s.SkipValue()
+		}
+		return e.walkObject(s, node, fn)
+	case _BracketLeft:
+		if node.elements == nil {
+			return s.SkipValue()
+		}
+		return e.walkArray(s, node.elements, fn)
+	default:
+		return s.SkipValue()
+	}
+}
+
+// walkObject matches the members of the object at s against the children of node
+func (e *JSONExtractor) walkObject(s JSONScanner, node *jsonPathNode, fn JSONExtractFunc) error {
+
+	s.Advance()
+	s.SkipWhitespace()
+
+	if s.Peek() == _BraceRight {
+		s.Advance()
+		return nil
+	}
+
+	for {
+		key, err := s.ReadKey()
+
+		if err != nil {
+			return err
+		}
+
+		if err := s.Expect(_ColonChar); err != nil {
+			return err
+		}
+
+		if child, ok := node.children[key]; ok {
+			err = e.walk(s, child, fn)
+		} else {
+			err = s.SkipValue()
+		}
+
+		if err != nil {
+			return err
+		}
+
+		s.SkipWhitespace()
+
+		if s.Peek() == _BraceRight {
+			s.Advance()
+			return nil
+		}
+
+		if err := s.Expect(_CommaChar); err != nil {
+			return err
+		}
+	}
+}
+
+// walkArray matches every element of the array at s against elements
+func (e *JSONExtractor) walkArray(s JSONScanner, elements *jsonPathNode, fn JSONExtractFunc) error {
+
+	s.Advance()
+	s.SkipWhitespace()
+
+	if s.Peek() == _BracketRight {
+		s.Advance()
+		return nil
+	}
+
+	for {
+		if err := e.walk(s, elements, fn); err != nil {
+			return err
+		}
+
+		s.SkipWhitespace()
+
+		if s.Peek() == _BracketRight {
+			s.Advance()
+			return nil
+		}
+
+		if err := s.Expect(_CommaChar); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonStopped turns ErrJSONStop, possibly wrapped, into a normal end
+func jsonStopped(err error) error {
+	if errors.Is(err, ErrJSONStop) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestJSONExtractor(t *testing.T) {
+	doc := `{
+		"level": "info",
+		"user": {"id": 9007199254740993, "name": "gopher", "roles": ["a", "b"]},
+		"items": [{"price": 1.5, "sku": "x"}, {"sku": "y"}, {"price": 2}],
+		"payload": {"huge": [1, 2, 3, {"nested": "skip me"}]},
+		"user.id": "literal"
+	}`
+
+	e := NewJSONExtractor("user.id", "items.#.price", "user", "user.roles.#", "missing.path")
+
+	want := []string{
+		"user " + `{"id": 9007199254740993, "name": "gopher", "roles": ["a", "b"]}`,
+		"user.id 9007199254740993",
+		`user.roles.# "a"`,
+		`user.roles.# "b"`,
+		"items.#.price 1.5",
+		"items.#.price 2",
+	}
+
+	run := func(t *testing.T, extract func(fn JSONExtractFunc) error) {
+		var got []string
+		err := extract(func(path string, raw []byte, kind JSONTokenKind) error {
+			got = append(got, path+" "+string(raw))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	t.Run("Bytes", func(t *testing.T) {
+		run(t, func(fn JSONExtractFunc) error { return e.Extract([]byte(doc), fn) })
+	})
+
+	t.Run("Reader", func(t *testing.T) {
+		run(t, func(fn JSONExtractFunc) error {
+			return e.ExtractReader(iotest.OneByteReader(strings.NewReader(doc)), fn)
+		})
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		count := 0
+		err := e.Extract([]byte(doc), func(path string, raw []byte, kind JSONTokenKind) error {
+			count++
+			if kind != JSONTokenObjectStart {
+				t.Errorf("expected object kind for %s, got %v", path, kind)
+			}
+			return ErrJSONStop
+		})
+		if err != nil || count != 1 {
+			t.Errorf("expected one match and no error, got %d %v", count, err)
+		}
+	})
+}
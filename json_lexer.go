@@ -1,10 +1,14 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"strconv"
-	"strings"
 	"sync"
+	"unicode/utf8"
 )
 
 const (
@@ -17,6 +21,16 @@ const (
 	_BraceRight   = '}'
 )
 
+// JSONInvalidUTF8 selects how JSONLexer handles invalid UTF-8 in strings
+type JSONInvalidUTF8 uint8
+
+const (
+	// JSONInvalidUTF8Replace replaces invalid bytes and lone surrogates with U+FFFD
+	JSONInvalidUTF8Replace JSONInvalidUTF8 = iota
+	// JSONInvalidUTF8Reject returns an error for invalid bytes and lone surrogates
+	JSONInvalidUTF8Reject
+)
+
 var jsonLexerPool = sync.Pool{
 	New: func() any {
 		return &JSONLexer{}
@@ -30,6 +44,16 @@ func CreateJSONLexer(data []byte) *JSONLexer {
 	lexer.pos = 0
 	lexer.line = 1
 	lexer.column = 1
+	lexer.base = 0
+	lexer.lenient = false
+	lexer.strict = false
+	lexer.limits = JSONLimits{}
+	lexer.tokens = 0
+	lexer.invalidUTF8 = JSONInvalidUTF8Replace
+	lexer.valueOptions = JSONValueOptions{}
+	lexer.skipped = 0
+	lexer.separated = true
+	lexer.tok.reset()
 	return lexer
 }
 
@@ -39,6 +63,17 @@ func ReleaseJSONLexer(lexer *JSONLexer) {
 	lexer.pos = 0
 	lexer.line = 1
 	lexer.column = 1
+	lexer.base = 0
+	lexer.lenient = false
+	lexer.limits = JSONLimits{}
+	lexer.tokens = 0
+	lexer.invalidUTF8 = JSONInvalidUTF8Replace
+	lexer.skipped = 0
+	lexer.separated = true
+	lexer.tok.reset()
+	if cap(lexer.scratch) > jsonReaderMaxPooledSize {
+		lexer.scratch = nil
+	}
 	jsonLexerPool.Put(lexer)
 }
 
@@ -48,6 +83,22 @@ type JSONLexer struct {
 	pos    int
 	line   int
 	column int
+	base   int // offset of data in the whole input, for errors
+	tok    jsonTokenizer
+	limits JSONLimits
+	tokens int
+
+	skipTok jsonTokenizer
+	scratch []byte // unescaped strings of ReadStringBytes
+
+	invalidUTF8  JSONInvalidUTF8
+	valueOptions JSONValueOptions
+	lenient      bool
+	strict       bool
+
+	// trailing commas in lenient mode
+	skipped   int  // pos where SkipWhitespace last stopped
+	separated bool // the last token read was '{', '[', ',' or ':', so a comma is not trailing
 }
 
 // Position returns current line and column for error reporting
@@ -55,6 +106,35 @@ func (l *JSONLexer) Position() (int, int) {
 	return l.line, l.column
 }
 
+// SetInvalidUTF8 sets how ReadString handles invalid UTF-8 and lone surrogates
+// The default is JSONInvalidUTF8Replace
+func (l *JSONLexer) SetInvalidUTF8(mode JSONInvalidUTF8) {
+	l.invalidUTF8 = mode
+}
+
+// SetLenient turns on JSONC/JSON5 style input for hand-edited files
+// Comments, trailing commas, single-quoted strings and unquoted object keys are then accepted
+// In lenient mode ReadKey also reads a bare identifier as an unquoted key
+// Turning lenient mode on turns strict mode off
+func (l *JSONLexer) SetLenient(lenient bool) {
+	l.lenient = lenient
+	l.tok.lenient = lenient
+	if lenient {
+		l.strict = false
+	}
+}
+
+// SetStrict makes numbers and literals follow RFC 8259 exactly
+// Numbers with leading zeros are rejected, and numbers, true, false and null
+// must be followed by whitespace, ',', ']', '}' or the end of input
+// Turning strict mode on turns lenient mode off
+func (l *JSONLexer) SetStrict(strict bool) {
+	l.strict = strict
+	if strict {
+		l.SetLenient(false)
+	}
+}
+
 // Peek returns the next byte in the input without advancing the lexer
 // If the lexer is at the end of the input, it returns 0
 // This is useful for lookahead when parsing JSON
@@ -82,15 +162,88 @@ func (l *JSONLexer) Advance() {
 
 // SkipWhitespace advances the lexer until it reaches a non-whitespace character
 // This is useful for skipping over whitespace between JSON tokens
+// In lenient mode comments and trailing commas are skipped as well
 func (l *JSONLexer) SkipWhitespace() {
+	// a token was read since the last call, its last byte tells whether a comma may follow
+	if l.pos != l.skipped && l.pos > 0 {
+		switch l.data[l.pos-1] {
+		case _BraceLeft, _BracketLeft, _CommaChar, _ColonChar:
+			l.separated = true
+		default:
+			l.separated = false
+		}
+	}
+
 	for l.pos < l.len {
 		switch l.data[l.pos] {
 		case ' ', '\r', '\n', '\t':
 			l.Advance()
-		default:
-			return
+			continue
+		case '/':
+			if l.lenient && l.skipComment() {
+				continue
+			}
+		case _CommaChar:
+			if l.lenient && l.skipTrailingComma() {
+				continue
+			}
+		}
+		break
+	}
+
+	l.skipped = l.pos
+}
+
+// skipComment skips a // or /* */ comment at pos
+// An unterminated block comment is left in place for the caller to reject
+func (l *JSONLexer) skipComment() bool {
+	if l.pos+1 >= l.len {
+		return false
+	}
+	switch l.data[l.pos+1] {
+	case '/':
+		for l.pos < l.len && l.data[l.pos] != '\n' {
+			l.Advance()
 		}
+		return true
+	case '*':
+		end := bytes.Index(l.data[l.pos+2:l.len], []byte("*/"))
+		if end < 0 {
+			return false
+		}
+		for stop := l.pos + 2 + end + 2; l.pos < stop; {
+			l.Advance()
+		}
+		return true
 	}
+	return false
+}
+
+// skipTrailingComma skips a comma at pos that only precedes the end of an object or array
+func (l *JSONLexer) skipTrailingComma() bool {
+	// a comma right after an opening bracket, a colon or another comma is not trailing
+	if l.separated {
+		return false
+	}
+
+	pos, line, column := l.pos, l.line, l.column
+	l.Advance()
+	for l.pos < l.len {
+		switch l.data[l.pos] {
+		case ' ', '\r', '\n', '\t':
+			l.Advance()
+			continue
+		case '/':
+			if l.skipComment() {
+				continue
+			}
+		case _BraceRight, _BracketRight:
+			return true
+		}
+		break
+	}
+	l.pos, l.line, l.column = pos, line, column
+	return false
 }
 
 // Expect checks if the next byte in the input is equal to the given byte
@@ -99,11 +252,8 @@ func (l *JSONLexer) SkipWhitespace() {
 // This is useful for checking if the next byte is a specific character
 func (l *JSONLexer) Expect(c byte) error {
 	l.SkipWhitespace()
-	if l.pos >= l.len {
-		return fmt.Errorf("line %d, column %d: expected '%c', got EOF", l.line, l.column, c)
-	}
-	if l.data[l.pos] != c {
-		return fmt.Errorf("line %d, column %d: expected '%c', got '%c'", l.line, l.column, c, l.Peek())
+	if l.pos >= l.len || l.data[l.pos] != c {
+		return l.syntaxError(jsonQuoteByte(c), l.got())
 	}
 	l.Advance()
 	return nil
@@ -113,86 +263,241 @@ func (l *JSONLexer) Expect(c byte) error {
 // and returns it as a string
 // The string may contain escaped characters
 func (l *JSONLexer) ReadString() (string, error) {
+	b, err := l.ReadStringBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
 
-	if err := l.Expect(_QuoteChar); err != nil {
+// ReadKey reads an object key
+// In lenient mode the key may also be a bare identifier, which ReadString rejects
+func (l *JSONLexer) ReadKey() (string, error) {
+	b, err := l.ReadKeyBytes()
+	if err != nil {
 		return "", err
 	}
+	return string(b), nil
+}
 
-	var sb strings.Builder
-	sb.Grow(32)
+// ReadKeyBytes reads an object key like ReadKey without allocating
+// The slice is only valid until the next call on the lexer
+func (l *JSONLexer) ReadKeyBytes() ([]byte, error) {
+	l.SkipWhitespace()
+	if l.lenient && isJSONIdentStart(l.Peek()) {
+		if err := l.countToken(); err != nil {
+			return nil, err
+		}
+		return l.readIdentifier(), nil
+	}
+	return l.ReadStringBytes()
+}
+
+// ReadStringBytes reads a JSON string like ReadString without allocating
+// A string without escapes is returned as a subslice of the input,
+// otherwise it is unescaped into a scratch buffer owned by the lexer
+// The slice is only valid until the next call on the lexer
+func (l *JSONLexer) ReadStringBytes() ([]byte, error) {
+
+	quote := byte(_QuoteChar)
+
+	l.SkipWhitespace()
+
+	if err := l.countToken(); err != nil {
+		return nil, err
+	}
+
+	if l.lenient && l.Peek() == '\'' {
+		quote = '\''
+	}
+
+	if err := l.Expect(quote); err != nil {
+		return nil, err
+	}
+
+	start := l.pos
+
+	// most strings have no escapes and can be returned in place
+	end := start
+	for end < l.len && l.data[end] != quote && l.data[end] != '\\' && l.data[end] >= 0x20 {
+		end++
+	}
+
+	if end < l.len && l.data[end] == quote && utf8.Valid(l.data[start:end]) {
+		l.pos = end
+		l.column += utf8.RuneCount(l.data[start:end])
+		if err := l.checkStringLength(end - start); err != nil {
+			return nil, err
+		}
+		l.Advance()
+		return l.data[start:end], nil
+	}
+
+	buf := l.scratch[:0]
 
 	for l.pos < l.len {
 
 		ch := l.data[l.pos]
 
-		if ch == _QuoteChar {
+		if ch == quote {
+			if err := l.checkStringLength(l.pos - start); err != nil {
+				return nil, err
+			}
 			l.Advance()
-			return sb.String(), nil
+			l.scratch = buf
+			return buf, nil
 		}
 
 		if ch == '\\' {
 			l.Advance()
 			if l.pos >= l.len {
-				return "", fmt.Errorf("line %d, column %d: unterminated string escape", l.line, l.column)
+				return nil, l.syntaxError("escape character", "EOF")
 			}
 
 			switch l.data[l.pos] {
 			case _QuoteChar:
-				sb.WriteByte(_QuoteChar)
+				buf = append(buf, _QuoteChar)
 			case '\\':
-				sb.WriteByte('\\')
+				buf = append(buf, '\\')
 			case '/':
-				sb.WriteByte('/')
+				buf = append(buf, '/')
+			case '\'':
+				if !l.lenient {
+					return nil, l.syntaxError("escape character", jsonQuoteByte('\''))
+				}
+				buf = append(buf, '\'')
 			case 'b':
-				sb.WriteByte('\b')
+				buf = append(buf, '\b')
 			case 'f':
-				sb.WriteByte('\f')
+				buf = append(buf, '\f')
 			case 'n':
-				sb.WriteByte('\n')
+				buf = append(buf, '\n')
 			case 'r':
-				sb.WriteByte('\r')
+				buf = append(buf, '\r')
 			case 't':
-				sb.WriteByte('\t')
+				buf = append(buf, '\t')
 			case 'u':
 				l.Advance()
-				if l.pos+4 > l.len {
-					return "", fmt.Errorf("line %d, column %d: incomplete unicode escape", l.line, l.column)
-				}
-				hex := string(l.data[l.pos : l.pos+4])
-				val, err := strconv.ParseUint(hex, 16, 16)
+				r, err := l.readUnicodeEscape()
 				if err != nil {
-					return "", fmt.Errorf("line %d, column %d: invalid unicode escape: %s", l.line, l.column, hex)
+					return nil, err
 				}
-				sb.WriteRune(rune(val))
-				l.pos += 3
-				l.column += 3
+				buf = utf8.AppendRune(buf, r)
+				continue
 			default:
-				return "", fmt.Errorf("line %d, column %d: invalid escape character: \\%c", l.line, l.column, l.data[l.pos])
+				return nil, l.syntaxError("escape character", jsonQuoteByte(l.data[l.pos]))
 			}
 		} else if ch < 0x20 {
-			return "", fmt.Errorf("line %d, column %d: unescaped control character", l.line, l.column)
+			return nil, l.syntaxErrorMsg("unescaped control character "+jsonQuoteByte(ch), nil)
+		} else if ch < utf8.RuneSelf {
+			buf = append(buf, ch)
 		} else {
-			sb.WriteByte(ch)
+			r, size := utf8.DecodeRune(l.data[l.pos:l.len])
+			if r == utf8.RuneError && size == 1 {
+				if l.invalidUTF8 == JSONInvalidUTF8Reject {
+					return nil, l.syntaxErrorMsg("invalid UTF-8 "+jsonQuoteByte(ch), nil)
+				}
+				buf = utf8.AppendRune(buf, utf8.RuneError)
+			} else {
+				buf = append(buf, l.data[l.pos:l.pos+size]...)
+			}
+			l.pos += size
+			l.column++
+			continue
 		}
 		l.Advance()
 	}
 
-	return "", fmt.Errorf("line %d, column %d: unterminated string", l.line, l.column)
+	l.scratch = buf
+
+	return nil, l.syntaxErrorMsg("unterminated string", nil)
+}
+
+// readUnicodeEscape reads the XXXX of a \uXXXX escape
+// A high surrogate followed by a \uXXXX low surrogate is combined into one rune
+// A lone surrogate is replaced by U+FFFD or rejected, depending on SetInvalidUTF8
+func (l *JSONLexer) readUnicodeEscape() (rune, error) {
+	r, err := l.readHex4()
+	if err != nil {
+		return 0, err
+	}
+
+	if isHighSurrogate(r) && l.pos+6 <= l.len && l.data[l.pos] == '\\' && l.data[l.pos+1] == 'u' {
+		pos, column := l.pos, l.column
+		l.pos += 2
+		l.column += 2
+		low, err := l.readHex4()
+		if err != nil {
+			return 0, err
+		}
+		if isLowSurrogate(low) {
+			return decodeRune(r, low), nil
+		}
+		// not a pair, the second escape is read on its own
+		l.pos, l.column = pos, column
+	}
+
+	if isSurrogate(r) {
+		if l.invalidUTF8 == JSONInvalidUTF8Reject {
+			return 0, l.syntaxErrorMsg(fmt.Sprintf("invalid unicode surrogate \\u%04X", r), nil)
+		}
+		return utf8.RuneError, nil
+	}
+
+	return r, nil
+}
+
+// readHex4 reads 4 hex digits and advances past them
+func (l *JSONLexer) readHex4() (rune, error) {
+	if l.pos+4 > l.len {
+		return 0, l.syntaxError("4 hex digits", "EOF")
+	}
+	val, err := parseHex4(l.data[l.pos : l.pos+4])
+	if err != nil {
+		return 0, l.syntaxError("4 hex digits", strconv.Quote(string(l.data[l.pos:l.pos+4])))
+	}
+	l.pos += 4
+	l.column += 4
+	return rune(val), nil
 }
 
 // ReadNumber reads a JSON number from the input
 // and returns it as a float64
 // The number may be an integer or a floating point number
 func (l *JSONLexer) ReadNumber() (float64, error) {
+	raw, err := l.ReadNumberRaw()
+	if err != nil {
+		return 0, err
+	}
+	val, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil {
+		return 0, l.syntaxErrorMsg("invalid number", err)
+	}
+	return val, nil
+}
+
+// ReadNumberRaw reads a JSON number from the input
+// and returns its literal bytes without converting them
+// The returned slice refers to the input
+func (l *JSONLexer) ReadNumberRaw() ([]byte, error) {
 	l.SkipWhitespace()
 	start := l.pos
 
+	if err := l.countToken(); err != nil {
+		return nil, err
+	}
+
 	if l.Peek() == '-' {
 		l.Advance()
 	}
 
 	if l.pos >= l.len || !isDigit(l.data[l.pos]) {
-		return 0, fmt.Errorf("line %d, column %d: invalid number", l.line, l.column)
+		return nil, l.syntaxError("digit", l.got())
+	}
+
+	if l.strict && l.data[l.pos] == '0' && l.pos+1 < l.len && isDigit(l.data[l.pos+1]) {
+		l.Advance()
+		return nil, l.syntaxErrorMsg("leading zero in number", nil)
 	}
 
 	for l.pos < l.len && isDigit(l.data[l.pos]) {
@@ -202,7 +507,7 @@ func (l *JSONLexer) ReadNumber() (float64, error) {
 	if l.pos < l.len && l.data[l.pos] == '.' {
 		l.Advance()
 		if l.pos >= l.len || !isDigit(l.data[l.pos]) {
-			return 0, fmt.Errorf("line %d, column %d: invalid decimal number", l.line, l.column)
+			return nil, l.syntaxError("digit after decimal point", l.got())
 		}
 		for l.pos < l.len && isDigit(l.data[l.pos]) {
 			l.Advance()
@@ -215,19 +520,73 @@ func (l *JSONLexer) ReadNumber() (float64, error) {
 			l.Advance()
 		}
 		if l.pos >= l.len || !isDigit(l.data[l.pos]) {
-			return 0, fmt.Errorf("line %d, column %d: invalid exponent", l.line, l.column)
+			return nil, l.syntaxError("digit in exponent", l.got())
 		}
 		for l.pos < l.len && isDigit(l.data[l.pos]) {
 			l.Advance()
 		}
 	}
 
-	numStr := string(l.data[start:l.pos])
-	val, err := strconv.ParseFloat(numStr, 64)
+	if err := l.checkDelimiter("end of number"); err != nil {
+		return nil, err
+	}
+
+	return l.data[start:l.pos], nil
+}
+
+// ReadJSONNumber reads a JSON number from the input
+// and returns its literal text as a json.Number
+// This keeps the exact value for the caller to convert later
+func (l *JSONLexer) ReadJSONNumber() (json.Number, error) {
+	raw, err := l.ReadNumberRaw()
 	if err != nil {
-		return 0, fmt.Errorf("line %d, column %d: %v", l.line, l.column, err)
+		return "", err
 	}
-	return val, nil
+	return json.Number(raw), nil
+}
+
+// ReadInt64 reads a JSON number from the input
+// and returns it as an int64
+// Integer literals are parsed digit by digit, so values above 2^53 are exact
+// A fraction or exponent is accepted only if the value is an integer within 2^53
+func (l *JSONLexer) ReadInt64() (int64, error) {
+	raw, err := l.ReadNumberRaw()
+	if err != nil {
+		return 0, err
+	}
+	neg, mag, err := parseJSONInteger(raw)
+	if err != nil {
+		return 0, l.syntaxErrorMsg("", err)
+	}
+	if neg {
+		if mag > 1<<63 {
+			return 0, l.syntaxErrorMsg("", ErrJSONIntegerRange)
+		}
+		return int64(^mag + 1), nil
+	}
+	if mag > math.MaxInt64 {
+		return 0, l.syntaxErrorMsg("", ErrJSONIntegerRange)
+	}
+	return int64(mag), nil
+}
+
+// ReadUint64 reads a JSON number from the input
+// and returns it as a uint64
+// Integer literals are parsed digit by digit, so values above 2^53 are exact
+// If the number is negative, an error is returned
+func (l *JSONLexer) ReadUint64() (uint64, error) {
+	raw, err := l.ReadNumberRaw()
+	if err != nil {
+		return 0, err
+	}
+	neg, mag, err := parseJSONInteger(raw)
+	if err != nil {
+		return 0, l.syntaxErrorMsg("", err)
+	}
+	if neg && mag != 0 {
+		return 0, l.syntaxError("unsigned integer", "negative number")
+	}
+	return mag, nil
 }
 
 // ReadInt reads a JSON number from the input
@@ -235,27 +594,24 @@ func (l *JSONLexer) ReadNumber() (float64, error) {
 // The number may be an integer or a floating point number
 // If the number is a floating point number, an error is returned
 func (l *JSONLexer) ReadInt() (int, error) {
-	val, err := l.ReadNumber()
+	val, err := l.ReadInt64()
 	if err != nil {
 		return 0, err
 	}
-	if float64(int(val)) != val {
-		return 0, fmt.Errorf("line %d, column %d: expected integer, got float", l.line, l.column)
+	if val < math.MinInt || val > math.MaxInt {
+		return 0, l.syntaxErrorMsg("", ErrJSONIntegerRange)
 	}
 	return int(val), nil
 }
 
 // ReadUint reads a JSON number as an unsigned integer, rejecting negatives and floats
 func (l *JSONLexer) ReadUint() (uint, error) {
-	val, err := l.ReadNumber()
+	val, err := l.ReadUint64()
 	if err != nil {
 		return 0, err
 	}
-	if val < 0 {
-		return 0, fmt.Errorf("line %d, column %d: expected unsigned integer, got negative number", l.line, l.column)
-	}
-	if float64(uint(val)) != val {
-		return 0, fmt.Errorf("line %d, column %d: expected unsigned integer, got float", l.line, l.column)
+	if val > math.MaxUint {
+		return 0, l.syntaxErrorMsg("", ErrJSONIntegerRange)
 	}
 	return uint(val), nil
 }
@@ -266,24 +622,27 @@ func (l *JSONLexer) ReadUint() (uint, error) {
 // If the number is a floating point number or a negative integer, an error is returned
 func (l *JSONLexer) ReadBool() (bool, error) {
 	l.SkipWhitespace()
+	if err := l.countToken(); err != nil {
+		return false, err
+	}
 	if l.pos >= l.len {
-		return false, fmt.Errorf("line %d, column %d: invalid boolean value", l.line, l.column)
+		return false, l.syntaxError("boolean", l.got())
 	}
 	switch l.data[l.pos] {
 	case 't':
 		if l.pos+4 <= l.len && l.data[l.pos+1] == 'r' && l.data[l.pos+2] == 'u' && l.data[l.pos+3] == 'e' {
 			l.pos += 4
 			l.column += 4
-			return true, nil
+			return true, l.checkDelimiter("end of literal")
 		}
 	case 'f':
 		if l.pos+5 <= l.len && l.data[l.pos+1] == 'a' && l.data[l.pos+2] == 'l' && l.data[l.pos+3] == 's' && l.data[l.pos+4] == 'e' {
 			l.pos += 5
 			l.column += 5
-			return false, nil
+			return false, l.checkDelimiter("end of literal")
 		}
 	}
-	return false, fmt.Errorf("line %d, column %d: invalid boolean value", l.line, l.column)
+	return false, l.syntaxError("boolean", l.got())
 }
 
 // ReadNull reads a JSON null value from the input
@@ -291,12 +650,36 @@ func (l *JSONLexer) ReadBool() (bool, error) {
 // If the next 4 bytes are not "null", an error is returned
 func (l *JSONLexer) ReadNull() error {
 	l.SkipWhitespace()
+	if err := l.countToken(); err != nil {
+		return err
+	}
 	if l.pos+4 <= l.len && l.data[l.pos] == 'n' && l.data[l.pos+1] == 'u' && l.data[l.pos+2] == 'l' && l.data[l.pos+3] == 'l' {
 		l.pos += 4
 		l.column += 4
+		return l.checkDelimiter("end of literal")
+	}
+	return l.syntaxError("null", l.got())
+}
+
+// checkDelimiter checks in strict mode that a number or literal ends before the next byte
+func (l *JSONLexer) checkDelimiter(expected string) error {
+	if !l.strict || l.pos >= l.len {
+		return nil
+	}
+	switch l.data[l.pos] {
+	case ' ', '\t', '\r', '\n', _CommaChar, _BracketRight, _BraceRight:
 		return nil
 	}
-	return fmt.Errorf("line %d, column %d: expected null", l.line, l.column)
+	return l.syntaxError(expected, l.got())
+}
+
+// ExpectEOF checks only whitespace is left after the document
+func (l *JSONLexer) ExpectEOF() error {
+	l.SkipWhitespace()
+	if l.pos < l.len {
+		return l.syntaxErrorMsg("unexpected data after value", nil)
+	}
+	return nil
 }
 
 // ReadArrayString reads a JSON array of strings
@@ -365,74 +748,146 @@ func (l *JSONLexer) ReadArrayFloat64() ([]float64, error) {
 	return result, nil
 }
 
+// ReadRawValue reads the next JSON value and returns its bytes undecoded
+// The returned slice refers to the input
+func (l *JSONLexer) ReadRawValue() ([]byte, error) {
+	l.SkipWhitespace()
+	start := l.pos
+	if err := l.SkipValue(); err != nil {
+		return nil, err
+	}
+	return l.data[start:l.pos], nil
+}
+
 // SkipValue skips over a JSON value in the input
 // This is useful for skipping over JSON values when parsing JSON
-// It can be used to skip over JSON objects, arrays, strings, numbers, booleans, and null values
+// Containers are skipped token by token without recursion, nesting is bounded by SetLimits
 func (l *JSONLexer) SkipValue() error {
-	l.SkipWhitespace()
-	switch l.Peek() {
-	case _BraceLeft:
-		l.Advance()
-		if l.Peek() == _BraceRight {
-			l.Advance()
+	l.skipTok.reset()
+	l.skipTok.lenient = l.lenient
+	for {
+		if _, err := l.next(&l.skipTok); err != nil {
+			if err == io.EOF {
+				return l.syntaxError("value", "EOF")
+			}
+			return err
+		}
+		if l.skipTok.state == jsonStateDone {
 			return nil
 		}
-		for {
-			if _, err := l.ReadString(); err != nil {
-				return err
-			}
-			if err := l.Expect(_ColonChar); err != nil {
-				return err
-			}
-			if err := l.SkipValue(); err != nil {
-				return err
-			}
-			l.SkipWhitespace()
-			if l.Peek() == _BraceRight {
-				l.Advance()
-				break
-			}
-			if err := l.Expect(_CommaChar); err != nil {
-				return err
+	}
+}
+
+// Next reads the next token of the document
+// It returns io.EOF after the last top-level value
+// Next keeps its own view of the document structure, so it should not be mixed with the Read methods
+func (l *JSONLexer) Next() (JSONToken, error) {
+	return l.next(&l.tok)
+}
+
+// next reads the next token as tracked by t
+func (l *JSONLexer) next(t *jsonTokenizer) (JSONToken, error) {
+	for {
+		l.SkipWhitespace()
+		if l.pos >= l.len {
+			if t.atEOF() {
+				return JSONToken{}, io.EOF
 			}
+			return JSONToken{}, l.syntaxError(t.expected(), "EOF")
 		}
-	case _BracketLeft:
-		l.Advance()
-		if l.Peek() == _BracketRight {
+
+		kind, skip, expected := t.step(l.data[l.pos])
+		if expected != "" {
+			return JSONToken{}, l.syntaxError(expected, l.got())
+		}
+		if skip {
 			l.Advance()
-			return nil
+			continue
 		}
-		for {
-			if err := l.SkipValue(); err != nil {
-				return err
+
+		tok := JSONToken{Kind: kind, Offset: l.pos, Line: l.line, Column: l.column}
+
+		var err error
+		switch kind {
+		case JSONTokenKey:
+			tok.Value, err = l.ReadKey()
+		case JSONTokenString:
+			tok.Value, err = l.ReadString()
+		case JSONTokenNumber:
+			_, err = l.ReadNumberRaw()
+		case JSONTokenBool:
+			_, err = l.ReadBool()
+		case JSONTokenNull:
+			err = l.ReadNull()
+		case JSONTokenObjectStart, JSONTokenArrayStart:
+			if err = l.countToken(); err == nil {
+				err = l.checkDepth(len(t.stack))
 			}
-			l.SkipWhitespace()
-			if l.Peek() == _BracketRight {
+			if err == nil {
 				l.Advance()
-				break
 			}
-			if err := l.Expect(_CommaChar); err != nil {
-				return err
+		default:
+			if err = l.countToken(); err == nil {
+				l.Advance()
 			}
 		}
-	case _QuoteChar:
-		_, err := l.ReadString()
-		return err
-	case 't', 'f':
-		_, err := l.ReadBool()
-		return err
-	case 'n':
-		return l.ReadNull()
-	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		_, err := l.ReadNumber()
-		return err
-	default:
-		return fmt.Errorf("line %d, column %d: unexpected character: %c", l.line, l.column, l.Peek())
+		if err != nil {
+			return JSONToken{}, err
+		}
+
+		tok.Raw = l.data[tok.Offset:l.pos]
+		return tok, nil
 	}
-	return nil
+}
+
+// parseJSONInteger parses a JSON number literal into sign and magnitude
+// Plain digits are accumulated exactly with overflow detection
+// Literals with a fraction or exponent must be integral and no larger than 2^53
+func parseJSONInteger(raw []byte) (bool, uint64, error) {
+	neg := false
+	if len(raw) > 0 && raw[0] == '-' {
+		neg = true
+		raw = raw[1:]
+	}
+
+	var mag uint64
+	i := 0
+	for ; i < len(raw) && isDigit(raw[i]); i++ {
+		d := uint64(raw[i] - '0')
+		if mag > (math.MaxUint64-d)/10 {
+			return neg, 0, ErrJSONIntegerRange
+		}
+		mag = mag*10 + d
+	}
+	if i == len(raw) {
+		return neg, mag, nil
+	}
+
+	f, err := strconv.ParseFloat(string(raw), 64)
+	if err != nil || f != math.Trunc(f) {
+		return neg, 0, ErrJSONNotInteger
+	}
+	if f > 1<<53 {
+		return neg, 0, ErrJSONIntegerRange
+	}
+	return neg, uint64(f), nil
 }
 
 // isDigit returns true if the given byte is a digit character
 func isDigit(b byte) bool {
 	return b >= '0' && b <= '9'
 }
+
+// isJSONIdentStart reports whether c starts an unquoted key in lenient mode
+func isJSONIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// readIdentifier reads an unquoted key in lenient mode
+func (l *JSONLexer) readIdentifier() []byte {
+	start := l.pos
+	for l.pos < l.len && (isJSONIdentStart(l.data[l.pos]) || isDigit(l.data[l.pos])) {
+		l.Advance()
+	}
+	return l.data[start:l.pos]
+}
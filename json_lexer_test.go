@@ -0,0 +1,556 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestJSONLexerNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []JSONTokenKind
+		values  []string
+		wantErr bool
+	}{
+		{
+			name:  "Scalar",
+			input: " 12.5 ",
+			want:  []JSONTokenKind{JSONTokenNumber},
+		},
+		{
+			name:   "Object",
+			input:  `{"a": 1, "b": [true, null, "x"], "c": {}}`,
+			want:   []JSONTokenKind{JSONTokenObjectStart, JSONTokenKey, JSONTokenNumber, JSONTokenKey, JSONTokenArrayStart, JSONTokenBool, JSONTokenNull, JSONTokenString, JSONTokenArrayEnd, JSONTokenKey, JSONTokenObjectStart, JSONTokenObjectEnd, JSONTokenObjectEnd},
+			values: []string{"", "a", "", "b", "", "", "", "x", "", "c"},
+		},
+		{
+			name:  "Empty array",
+			input: `[]`,
+			want:  []JSONTokenKind{JSONTokenArrayStart, JSONTokenArrayEnd},
+		},
+		{
+			name:    "Missing colon",
+			input:   `{"a" 1}`,
+			want:    []JSONTokenKind{JSONTokenObjectStart, JSONTokenKey},
+			wantErr: true,
+		},
+		{
+			name:    "Trailing comma",
+			input:   `[1,]`,
+			want:    []JSONTokenKind{JSONTokenArrayStart, JSONTokenNumber},
+			wantErr: true,
+		},
+		{
+			name:    "Unclosed object",
+			input:   `{"a":1`,
+			want:    []JSONTokenKind{JSONTokenObjectStart, JSONTokenKey, JSONTokenNumber},
+			wantErr: true,
+		},
+		{
+			name:    "Mismatched close",
+			input:   `[1}`,
+			want:    []JSONTokenKind{JSONTokenArrayStart, JSONTokenNumber},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := CreateJSONLexer([]byte(tt.input))
+			defer ReleaseJSONLexer(l)
+
+			for i, kind := range tt.want {
+				tok, err := l.Next()
+				if err != nil {
+					t.Fatalf("token %d: unexpected error: %v", i, err)
+				}
+				if tok.Kind != kind {
+					t.Fatalf("token %d: expected %v, got %v", i, kind, tok.Kind)
+				}
+				if i < len(tt.values) && tt.values[i] != "" && tok.Value != tt.values[i] {
+					t.Errorf("token %d: expected value %q, got %q", i, tt.values[i], tok.Value)
+				}
+				if tt.input[tok.Offset:tok.Offset+len(tok.Raw)] != string(tok.Raw) {
+					t.Errorf("token %d: raw %q does not match offset %d", i, tok.Raw, tok.Offset)
+				}
+			}
+
+			_, err := l.Next()
+			if tt.wantErr {
+				if err == nil || errors.Is(err, io.EOF) {
+					t.Errorf("expected syntax error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, io.EOF) {
+				t.Errorf("expected io.EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestJSONLexerReadInt64(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int64
+		wantErr error
+	}{
+		{name: "Snowflake above 2^53", input: "9007199254740993", want: 9007199254740993},
+		{name: "Max int64", input: "9223372036854775807", want: 9223372036854775807},
+		{name: "Min int64", input: "-9223372036854775808", want: -9223372036854775808},
+		{name: "Overflow", input: "9223372036854775808", wantErr: ErrJSONIntegerRange},
+		{name: "Negative overflow", input: "-9223372036854775809", wantErr: ErrJSONIntegerRange},
+		{name: "Integral exponent", input: "1e3", want: 1000},
+		{name: "Integral fraction", input: "-2.0", want: -2},
+		{name: "Fraction", input: "1.5", wantErr: ErrJSONNotInteger},
+		{name: "Inexact exponent", input: "1e20", wantErr: ErrJSONIntegerRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := CreateJSONLexer([]byte(tt.input))
+			defer ReleaseJSONLexer(l)
+
+			got, err := l.ReadInt64()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %d %v", tt.wantErr, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONLexerReadUint64(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    uint64
+		wantErr bool
+	}{
+		{name: "Max uint64", input: "18446744073709551615", want: 18446744073709551615},
+		{name: "Overflow", input: "18446744073709551616", wantErr: true},
+		{name: "Negative zero", input: "-0", want: 0},
+		{name: "Negative", input: "-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := CreateJSONLexer([]byte(tt.input))
+			defer ReleaseJSONLexer(l)
+
+			got, err := l.ReadUint64()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONLexerReadStringUnicode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		mode    JSONInvalidUTF8
+		want    string
+		wantErr bool
+	}{
+		{name: "Surrogate pair", input: `"\ud83d\ude00"`, want: "😀"},
+		{name: "Raw multi-byte", input: `"héllo 世界"`, want: "héllo 世界"},
+		{name: "BMP escape", input: `"\u00e9\u4E16"`, want: "é世"},
+		{name: "Lone high surrogate", input: `"\ud83dx"`, want: "\ufffdx"},
+		{name: "High then non-low", input: `"\ud83d\u0041"`, want: "\ufffdA"},
+		{name: "Lone low surrogate", input: `"\ude00"`, want: "\ufffd"},
+		{name: "Invalid UTF-8 replaced", input: "\"a\xffb\"", want: "a\ufffdb"},
+		{name: "Invalid UTF-8 rejected", input: "\"a\xffb\"", mode: JSONInvalidUTF8Reject, wantErr: true},
+		{name: "Lone surrogate rejected", input: `"\ud83d"`, mode: JSONInvalidUTF8Reject, wantErr: true},
+		{name: "Pair accepted in reject mode", input: `"\ud83d\ude00"`, mode: JSONInvalidUTF8Reject, want: "😀"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := CreateJSONLexer([]byte(tt.input))
+			defer ReleaseJSONLexer(l)
+			l.SetInvalidUTF8(tt.mode)
+
+			got, err := l.ReadString()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONLexerReadStringBytes(t *testing.T) {
+	input := []byte(`["plain", "caf\u00e9 é", "tab\t", "x"]`)
+
+	l := CreateJSONLexer(input)
+	defer ReleaseJSONLexer(l)
+
+	if err := l.Expect('['); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"plain", "café é", "tab\t", "x"}
+	inPlace := []bool{true, false, false, true}
+
+	for i := range want {
+		if i > 0 {
+			if err := l.Expect(','); err != nil {
+				t.Fatal(err)
+			}
+		}
+		b, err := l.ReadStringBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want[i] {
+			t.Errorf("expected %q, got %q", want[i], b)
+		}
+		if shared := len(b) > 0 && &b[0] == &input[l.pos-1-len(b)]; shared != inPlace[i] {
+			t.Errorf("%q: expected in place %v, got %v", want[i], inPlace[i], shared)
+		}
+	}
+
+	// the fast path keeps columns in characters, like the escaped path
+	if line, column := l.Position(); line != 1 || column != 38 {
+		t.Errorf("expected 1:38, got %d:%d", line, column)
+	}
+
+	for _, bad := range []string{`"a`, "\"a\tb\"", `"a\qb"`} {
+		if _, err := CreateJSONLexer([]byte(bad)).ReadStringBytes(); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+
+	r := CreateJSONReader(iotest.OneByteReader(strings.NewReader(`"a\nb"`)))
+	defer ReleaseJSONReader(r)
+	if b, err := r.ReadStringBytes(); err != nil || string(b) != "a\nb" {
+		t.Errorf("expected %q, got %q %v", "a\nb", b, err)
+	}
+}
+
+func TestDecodeJSONAllocs(t *testing.T) {
+	data := []byte(`{"userID": 42, "unknown": [1, {"x": "y"}], "userRight": -7}`)
+
+	var auth Auth
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := auth.UnmarshalJSON(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if auth.UserID != 42 || auth.UserRight != -7 {
+		t.Errorf("unexpected result %+v", auth)
+	}
+
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestJSONLexerLenient(t *testing.T) {
+	input := `// settings
+	{
+		name: 'it\'s "ok"', /* inline */ "list": [1, 2, /* last */ 3,],
+		'nested': {a_1: "x", $b: null,}, // trailing
+	}`
+
+	l := CreateJSONLexer([]byte(input))
+	defer ReleaseJSONLexer(l)
+	l.SetLenient(true)
+
+	var got []string
+	for {
+		tok, err := l.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tok.Kind == JSONTokenKey || tok.Kind == JSONTokenString {
+			got = append(got, tok.Kind.String()+":"+tok.Value)
+		} else {
+			got = append(got, tok.Kind.String())
+		}
+	}
+
+	want := []string{
+		"ObjectStart", "Key:name", `String:it's "ok"`, "Key:list", "ArrayStart", "Number", "Number", "Number", "ArrayEnd",
+		"Key:nested", "ObjectStart", "Key:a_1", "String:x", "Key:$b", "Null", "ObjectEnd", "ObjectEnd",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestJSONLexerLenientBareWord(t *testing.T) {
+	// a bare identifier is a key, never a value
+	for _, input := range []string{`foo`, `true`, `null`} {
+		l := CreateJSONLexer([]byte(input))
+		l.SetLenient(true)
+		var syntaxErr *JSONSyntaxError
+		if s, err := l.ReadString(); !errors.As(err, &syntaxErr) {
+			t.Errorf("%s: expected *JSONSyntaxError, got %q %v", input, s, err)
+		}
+		ReleaseJSONLexer(l)
+	}
+
+	l := CreateJSONLexer([]byte(`{name: 'x', $id_2: "y"}`))
+	defer ReleaseJSONLexer(l)
+	l.SetLenient(true)
+
+	var keys []string
+	err := l.ReadObject(func(key string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil || strings.Join(keys, " ") != "name $id_2" {
+		t.Errorf("expected keys name $id_2, got %v %v", keys, err)
+	}
+
+	for _, input := range []string{`{name: foo}`, `[foo]`} {
+		v := CreateJSONLexer([]byte(input))
+		v.SetLenient(true)
+		if _, err := v.ReadValue(); err == nil {
+			t.Errorf("%s: ReadValue should reject a bare word value", input)
+		}
+		ReleaseJSONLexer(v)
+
+		v = CreateJSONLexer([]byte(input))
+		v.SetLenient(true)
+		if err := v.SkipValue(); err == nil {
+			t.Errorf("%s: SkipValue should reject a bare word value", input)
+		}
+		ReleaseJSONLexer(v)
+	}
+}
+
+func TestJSONLexerLenientTrailingComma(t *testing.T) {
+	// column is where a comma that is not trailing is rejected, 0 if the input is valid
+	tests := []struct {
+		input  string
+		column int
+	}{
+		{`[1 /*c*/ ,]`, 0},
+		{`[1, /*c*/ ]`, 0},
+		{"[1 // c\n,]", 0},
+		{"[\"a//b\" ,]", 0},
+		{`{"a": 1 /*c*/ , /*d*/ }`, 0},
+		{`[/*c*/ ,]`, 8},
+		{`[1, /*c*/ ,]`, 11},
+		{`[1,/* a/* */,]`, 13},
+		{"[1, // c\n,]", 1},
+		{`{/*c*/ ,}`, 8},
+		{`{"a": ,}`, 7},
+	}
+
+	read := map[string]func(l *JSONLexer) error{
+		"ReadValue": func(l *JSONLexer) error { _, err := l.ReadValue(); return err },
+		"SkipValue": func(l *JSONLexer) error { return l.SkipValue() },
+	}
+
+	for _, tt := range tests {
+		for name, fn := range read {
+			l := CreateJSONLexer([]byte(tt.input))
+			l.SetLenient(true)
+			err := fn(l)
+			if err == nil {
+				err = l.ExpectEOF()
+			}
+			var syntaxErr *JSONSyntaxError
+			if tt.column == 0 && err != nil {
+				t.Errorf("%s %q: unexpected error: %v", name, tt.input, err)
+			}
+			if tt.column != 0 && (!errors.As(err, &syntaxErr) || syntaxErr.Column != tt.column) {
+				t.Errorf("%s %q: expected error at column %d, got %v", name, tt.input, tt.column, err)
+			}
+			ReleaseJSONLexer(l)
+		}
+	}
+}
+
+// TestJSONConformance checks strict mode against RFC 8259, in the spirit of JSONTestSuite
+func TestJSONConformance(t *testing.T) {
+	accept := []string{
+		`[]`, `{}`, ` 1 `, `0`, `-0`, `[-0.0e+1]`, `[1E22]`, `[1e-2]`, `[123.456e78]`, `-1`,
+		`[true, false, null]`, `{"a":[{"b":""}]}`, `{"":0}`, `[[]]`, `[1,2]`, `"a\/b"`,
+		`"\ud834\udd1e"`, "[1\n,\t2\r]", `{"a":true}`, `[null]`,
+	}
+
+	reject := []string{
+		``, ` `, `[01]`, `[-01]`, `[00]`, `0123`, `[1.]`, `[.1]`, `[+1]`, `[1e]`, `[1e+]`, `[-]`, `[0x1]`,
+		`[Infinity]`, `[NaN]`, `[truex]`, `[nullable]`, `[falsey]`, `[fals]`, `[tru]`, `[True]`, `[1.5.3]`, `[-1x]`,
+		`[1 2]`, `[1,]`, `[1,,2]`, `{"a":1,}`, `{"a" 1}`, `{'a':1}`, `{1:2}`, `[1]x`, `[1] [2]`, `{"a":1}}`,
+		`["a\x"]`, "[\"\t\"]", `["unterminated]`, `/*c*/[1]`, `nul`, `truefalse`,
+	}
+
+	parse := func(s JSONScanner) error {
+		s.SetStrict(true)
+		if err := s.SkipValue(); err != nil {
+			return err
+		}
+		return s.ExpectEOF()
+	}
+
+	for _, valid := range []bool{true, false} {
+		inputs := accept
+		if !valid {
+			inputs = reject
+		}
+		for _, input := range inputs {
+			l := CreateJSONLexer([]byte(input))
+			r := CreateJSONReader(iotest.OneByteReader(strings.NewReader(input)))
+			v := CreateJSONLexer([]byte(input))
+			v.SetStrict(true)
+			_, valueErr := v.ReadValue()
+			if valueErr == nil {
+				valueErr = v.ExpectEOF()
+			}
+
+			for name, err := range map[string]error{"lexer": parse(l), "reader": parse(r), "ReadValue": valueErr} {
+				if valid && err != nil {
+					t.Errorf("%s %q: unexpected error: %v", name, input, err)
+				}
+				var syntaxErr *JSONSyntaxError
+				if !valid && !errors.As(err, &syntaxErr) {
+					t.Errorf("%s %q: expected *JSONSyntaxError, got %v", name, input, err)
+				}
+			}
+
+			ReleaseJSONLexer(l)
+			ReleaseJSONReader(r)
+			ReleaseJSONLexer(v)
+		}
+	}
+}
+
+func TestJSONLexerStrict(t *testing.T) {
+	tests := []struct {
+		input string
+		read  func(l *JSONLexer) error
+	}{
+		{input: `truex`, read: func(l *JSONLexer) error { _, err := l.ReadBool(); return err }},
+		{input: `nullable`, read: func(l *JSONLexer) error { return l.ReadNull() }},
+		{input: `0123`, read: func(l *JSONLexer) error { _, err := l.ReadNumber(); return err }},
+	}
+
+	for _, tt := range tests {
+		l := CreateJSONLexer([]byte(tt.input))
+		if err := tt.read(l); err != nil {
+			t.Errorf("%s: default mode should accept, got %v", tt.input, err)
+		}
+		ReleaseJSONLexer(l)
+
+		l = CreateJSONLexer([]byte(tt.input))
+		l.SetStrict(true)
+		if err := tt.read(l); err == nil {
+			t.Errorf("%s: strict mode should reject", tt.input)
+		}
+		ReleaseJSONLexer(l)
+	}
+
+	l := CreateJSONLexer([]byte(`[1] // c`))
+	defer ReleaseJSONLexer(l)
+	l.SetLenient(true)
+	l.SetStrict(true)
+	if err := l.SkipValue(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.ExpectEOF(); err == nil {
+		t.Error("strict mode should turn lenient mode off")
+	}
+}
+
+func TestJSONLimits(t *testing.T) {
+	deep := strings.Repeat("[", 1000000) + strings.Repeat("]", 1000000)
+
+	tests := []struct {
+		name    string
+		input   string
+		limits  JSONLimits
+		wantErr error
+	}{
+		{name: "default depth", input: deep, wantErr: ErrJSONTooDeep},
+		{name: "depth", input: `{"a": [[1]]}`, limits: JSONLimits{MaxDepth: 2}, wantErr: ErrJSONTooDeep},
+		{name: "depth ok", input: `{"a": [1]}`, limits: JSONLimits{MaxDepth: 2}},
+		{name: "string", input: `["abcdef"]`, limits: JSONLimits{MaxStringLength: 5}, wantErr: ErrJSONStringTooLong},
+		{name: "key", input: `{"abcdef": 1}`, limits: JSONLimits{MaxStringLength: 5}, wantErr: ErrJSONStringTooLong},
+		{name: "string ok", input: `["abcde"]`, limits: JSONLimits{MaxStringLength: 5}},
+		{name: "escaped string", input: `["a\nbc"]`, limits: JSONLimits{MaxStringLength: 4}, wantErr: ErrJSONStringTooLong},
+		{name: "escaped string ok", input: `["ab\"c"]`, limits: JSONLimits{MaxStringLength: 5}},
+		{name: "tokens", input: `[1, 2, 3, 4]`, limits: JSONLimits{MaxTokens: 5}, wantErr: ErrJSONTooManyTokens},
+		{name: "tokens ok", input: `[1, 2, 3, 4]`, limits: JSONLimits{MaxTokens: 6}},
+	}
+
+	check := func(t *testing.T, err, wantErr error) {
+		t.Helper()
+		if wantErr == nil {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			return
+		}
+		var syntaxErr *JSONSyntaxError
+		if !errors.Is(err, wantErr) || !errors.As(err, &syntaxErr) {
+			t.Errorf("expected %v, got %v", wantErr, err)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := CreateJSONLexer([]byte(tt.input))
+			defer ReleaseJSONLexer(l)
+			l.SetLimits(tt.limits)
+			check(t, l.SkipValue(), tt.wantErr)
+
+			r := CreateJSONReader(strings.NewReader(tt.input))
+			defer ReleaseJSONReader(r)
+			r.SetLimits(tt.limits)
+			check(t, r.SkipValue(), tt.wantErr)
+
+			_, _, err := SkipValueLimits(strings.NewReader(tt.input), make([]byte, 1<<21), 0, 0, tt.limits)
+			check(t, err, tt.wantErr)
+		})
+	}
+
+	// a long string is rejected before it is read in full
+	src := strings.NewReader(`["` + strings.Repeat("a", 1<<20) + `"]`)
+	_, _, err := SkipValueLimits(src, make([]byte, 512), 0, 0, JSONLimits{MaxStringLength: 1000})
+	check(t, err, ErrJSONStringTooLong)
+	if read := src.Size() - int64(src.Len()); read > 2048 {
+		t.Errorf("expected the string to be rejected early, read %d bytes", read)
+	}
+}
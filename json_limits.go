@@ -0,0 +1,78 @@
+package utils
+
+const (
+	// nesting allowed when no MaxDepth is set, as in encoding/json
+	jsonDefaultMaxDepth = 10000
+)
+
+// JSONLimits bounds what a document may use, to protect against hostile input
+// A zero MaxDepth means 10000 levels, other zero fields mean no limit
+type JSONLimits struct {
+	MaxDepth        int // nesting of objects and arrays
+	MaxStringLength int // bytes of a string in the input, between the quotes
+	MaxTokens       int // values, keys and brackets read in total
+}
+
+// SetLimits sets the limits enforced from now on and resets the token count
+// Violations return a *JSONSyntaxError wrapping ErrJSONTooDeep, ErrJSONStringTooLong or ErrJSONTooManyTokens
+func (l *JSONLexer) SetLimits(limits JSONLimits) {
+	l.limits = limits
+	l.tokens = 0
+}
+
+// SetLimits sets the limits enforced from now on and resets the token count
+// Violations return a *JSONSyntaxError wrapping ErrJSONTooDeep, ErrJSONStringTooLong or ErrJSONTooManyTokens
+func (r *JSONReader) SetLimits(limits JSONLimits) {
+	r.limits = limits
+	r.tokens = 0
+}
+
+// countToken counts a token read and checks MaxTokens
+func (l *JSONLexer) countToken() error {
+	l.tokens++
+	if l.limits.MaxTokens > 0 && l.tokens > l.limits.MaxTokens {
+		return l.syntaxErrorMsg("", ErrJSONTooManyTokens)
+	}
+	return nil
+}
+
+// checkDepth checks a nesting depth against MaxDepth
+func (l *JSONLexer) checkDepth(depth int) error {
+	if depth > maxJSONDepth(l.limits) {
+		return l.syntaxErrorMsg("", ErrJSONTooDeep)
+	}
+	return nil
+}
+
+// checkStringLength checks the length of a string in the input against MaxStringLength
+func (l *JSONLexer) checkStringLength(length int) error {
+	if l.limits.MaxStringLength > 0 && length > l.limits.MaxStringLength {
+		return l.syntaxErrorMsg("", ErrJSONStringTooLong)
+	}
+	return nil
+}
+
+// countToken counts a structural token read by the reader and checks MaxTokens
+func (r *JSONReader) countToken() error {
+	r.tokens++
+	if r.limits.MaxTokens > 0 && r.tokens > r.limits.MaxTokens {
+		return r.syntaxErrorMsg("", ErrJSONTooManyTokens)
+	}
+	return nil
+}
+
+// checkDepth checks a nesting depth against MaxDepth
+func (r *JSONReader) checkDepth(depth int) error {
+	if depth > maxJSONDepth(r.limits) {
+		return r.syntaxErrorMsg("", ErrJSONTooDeep)
+	}
+	return nil
+}
+
+// maxJSONDepth returns the nesting allowed by limits
+func maxJSONDepth(limits JSONLimits) int {
+	if limits.MaxDepth > 0 {
+		return limits.MaxDepth
+	}
+	return jsonDefaultMaxDepth
+}
@@ -0,0 +1,341 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+)
+
+const (
+	msgpackNil     = 0xc0
+	msgpackFalse   = 0xc2
+	msgpackTrue    = 0xc3
+	msgpackBin8    = 0xc4
+	msgpackBin16   = 0xc5
+	msgpackBin32   = 0xc6
+	msgpackFloat32 = 0xca
+	msgpackFloat64 = 0xcb
+	msgpackUint8   = 0xcc
+	msgpackUint16  = 0xcd
+	msgpackUint32  = 0xce
+	msgpackUint64  = 0xcf
+	msgpackInt8    = 0xd0
+	msgpackInt16   = 0xd1
+	msgpackInt32   = 0xd2
+	msgpackInt64   = 0xd3
+	msgpackStr8    = 0xd9
+	msgpackStr16   = 0xda
+	msgpackStr32   = 0xdb
+	msgpackArray16 = 0xdc
+	msgpackArray32 = 0xdd
+	msgpackMap16   = 0xde
+	msgpackMap32   = 0xdf
+
+	msgpackFixMap   = 0x80
+	msgpackFixArray = 0x90
+	msgpackFixStr   = 0xa0
+)
+
+// JSONToMsgPack transcodes the JSON document data to MessagePack
+// Integers that fit in int64 or uint64 are encoded exactly, other numbers as floats
+// Floats use float 32 when it is lossless
+func JSONToMsgPack(data []byte) ([]byte, error) {
+	return transcodeJSON(data, msgpackFormat{})
+}
+
+// MsgPackToJSON transcodes the MessagePack document data to JSON
+// Binary data becomes unpadded base64url strings, extension types are not supported
+// Map keys must be strings or integers, errors wrap ErrMsgPackInvalid
+func MsgPackToJSON(data []byte) ([]byte, error) {
+	return binaryToJSON(func(w *JSONWriter) error {
+		in := &binaryInput{data: data, name: "msgpack", invalid: ErrMsgPackInvalid}
+		if err := readMsgPack(in, w, 0); err != nil {
+			return err
+		}
+		return in.expectEnd()
+	})
+}
+
+// msgpackFormat appends values in MessagePack
+type msgpackFormat struct{}
+
+func (msgpackFormat) appendNull(dst []byte) []byte {
+	return append(dst, msgpackNil)
+}
+
+func (msgpackFormat) appendBool(dst []byte, v bool) []byte {
+	if v {
+		return append(dst, msgpackTrue)
+	}
+	return append(dst, msgpackFalse)
+}
+
+func (f msgpackFormat) appendInt(dst []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return f.appendUint(dst, uint64(v))
+	case v >= -32:
+		return append(dst, byte(v))
+	case v >= math.MinInt8:
+		return append(dst, msgpackInt8, byte(v))
+	case v >= math.MinInt16:
+		return appendUint16(append(dst, msgpackInt16), uint16(v))
+	case v >= math.MinInt32:
+		return appendUint32(append(dst, msgpackInt32), uint32(v))
+	default:
+		return appendUint64(append(dst, msgpackInt64), uint64(v))
+	}
+}
+
+func (msgpackFormat) appendUint(dst []byte, v uint64) []byte {
+	switch {
+	case v <= math.MaxInt8:
+		return append(dst, byte(v))
+	case v <= math.MaxUint8:
+		return append(dst, msgpackUint8, byte(v))
+	case v <= math.MaxUint16:
+		return appendUint16(append(dst, msgpackUint16), uint16(v))
+	case v <= math.MaxUint32:
+		return appendUint32(append(dst, msgpackUint32), uint32(v))
+	default:
+		return appendUint64(append(dst, msgpackUint64), v)
+	}
+}
+
+func (msgpackFormat) appendFloat(dst []byte, v float64) []byte {
+	if isFloat32(v) {
+		return appendUint32(append(dst, msgpackFloat32), math.Float32bits(float32(v)))
+	}
+	return appendUint64(append(dst, msgpackFloat64), math.Float64bits(v))
+}
+
+func (msgpackFormat) appendString(dst []byte, v string) []byte {
+	switch n := len(v); {
+	case n < 32:
+		dst = append(dst, msgpackFixStr|byte(n))
+	case n <= math.MaxUint8:
+		dst = append(dst, msgpackStr8, byte(n))
+	case n <= math.MaxUint16:
+		dst = appendUint16(append(dst, msgpackStr16), uint16(n))
+	default:
+		dst = appendUint32(append(dst, msgpackStr32), uint32(n))
+	}
+	return append(dst, v...)
+}
+
+func (msgpackFormat) appendArray(dst []byte, n uint64) []byte {
+	return appendMsgPackHead(dst, msgpackFixArray, msgpackArray16, msgpackArray32, n)
+}
+
+func (msgpackFormat) appendMap(dst []byte, n uint64) []byte {
+	return appendMsgPackHead(dst, msgpackFixMap, msgpackMap16, msgpackMap32, n)
+}
+
+// appendMsgPackHead appends a container header with n elements in the shortest form
+func appendMsgPackHead(dst []byte, fix, head16, head32 byte, n uint64) []byte {
+	switch {
+	case n < 16:
+		return append(dst, fix|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(dst, head16), uint16(n))
+	default:
+		return appendUint32(append(dst, head32), uint32(n))
+	}
+}
+
+// readMsgPack reads one object from in and writes it to w
+func readMsgPack(in *binaryInput, w *JSONWriter, depth int) error {
+
+	start := in.pos
+
+	c, err := in.byte()
+
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return w.WriteUint64(uint64(c))
+	case c >= 0xe0:
+		return w.WriteInt64(int64(int8(c)))
+	case c < msgpackFixArray:
+		return readMsgPackContainer(in, w, depth, true, uint64(c&0x0f))
+	case c < msgpackFixStr:
+		return readMsgPackContainer(in, w, depth, false, uint64(c&0x0f))
+	case c < msgpackNil:
+		s, err := in.bytes(uint64(c & 0x1f))
+		if err != nil {
+			return err
+		}
+		return w.WriteString(string(s))
+	}
+
+	switch c {
+	case msgpackNil:
+		return w.WriteNull()
+	case msgpackFalse:
+		return w.WriteBool(false)
+	case msgpackTrue:
+		return w.WriteBool(true)
+	case msgpackBin8, msgpackBin16, msgpackBin32:
+		b, err := readMsgPackBytes(in, 1<<(c-msgpackBin8))
+		if err != nil {
+			return err
+		}
+		return writeBinaryBytes(w, b)
+	case msgpackStr8, msgpackStr16, msgpackStr32:
+		s, err := readMsgPackBytes(in, 1<<(c-msgpackStr8))
+		if err != nil {
+			return err
+		}
+		return w.WriteString(string(s))
+	case msgpackFloat32:
+		v, err := in.uint(4)
+		if err != nil {
+			return err
+		}
+		return in.writeFloat(w, float64(math.Float32frombits(uint32(v))), start)
+	case msgpackFloat64:
+		v, err := in.uint(8)
+		if err != nil {
+			return err
+		}
+		return in.writeFloat(w, math.Float64frombits(v), start)
+	case msgpackUint8, msgpackUint16, msgpackUint32, msgpackUint64:
+		v, err := in.uint(1 << (c - msgpackUint8))
+		if err != nil {
+			return err
+		}
+		return w.WriteUint64(v)
+	case msgpackInt8, msgpackInt16, msgpackInt32, msgpackInt64:
+		v, err := readMsgPackInt(in, 1<<(c-msgpackInt8))
+		if err != nil {
+			return err
+		}
+		return w.WriteInt64(v)
+	case msgpackArray16, msgpackArray32:
+		n, err := in.uint(2 << (c - msgpackArray16))
+		if err != nil {
+			return err
+		}
+		return readMsgPackContainer(in, w, depth, false, n)
+	case msgpackMap16, msgpackMap32:
+		n, err := in.uint(2 << (c - msgpackMap16))
+		if err != nil {
+			return err
+		}
+		return readMsgPackContainer(in, w, depth, true, n)
+	}
+
+	in.pos = start
+
+	return in.errorf("unsupported type 0x%02x", c)
+}
+
+// readMsgPackContainer reads n elements of an array, or n members of a map, and writes them to w
+func readMsgPackContainer(in *binaryInput, w *JSONWriter, depth int, object bool, n uint64) error {
+
+	if err := in.checkDepth(depth + 1); err != nil {
+		return err
+	}
+
+	var err error
+
+	if object {
+		err = w.BeginObject()
+	} else {
+		err = w.BeginArray()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for i := uint64(0); i < n; i++ {
+
+		if object {
+			key, err := readMsgPackKey(in)
+			if err != nil {
+				return err
+			}
+			if err := w.Key(key); err != nil {
+				return err
+			}
+		}
+
+		if err := readMsgPack(in, w, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if object {
+		return w.EndObject()
+	}
+
+	return w.EndArray()
+}
+
+// readMsgPackKey reads a map key, integers are converted to their decimal text
+func readMsgPackKey(in *binaryInput) (string, error) {
+
+	start := in.pos
+
+	c, err := in.byte()
+
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return strconv.Itoa(int(c)), nil
+	case c >= 0xe0:
+		return strconv.Itoa(int(int8(c))), nil
+	case c >= msgpackFixStr && c < msgpackNil:
+		s, err := in.bytes(uint64(c & 0x1f))
+		return string(s), err
+	}
+
+	switch c {
+	case msgpackStr8, msgpackStr16, msgpackStr32:
+		s, err := readMsgPackBytes(in, 1<<(c-msgpackStr8))
+		return string(s), err
+	case msgpackUint8, msgpackUint16, msgpackUint32, msgpackUint64:
+		v, err := in.uint(1 << (c - msgpackUint8))
+		return strconv.FormatUint(v, 10), err
+	case msgpackInt8, msgpackInt16, msgpackInt32, msgpackInt64:
+		v, err := readMsgPackInt(in, 1<<(c-msgpackInt8))
+		return strconv.FormatInt(v, 10), err
+	}
+
+	in.pos = start
+
+	return "", in.errorf("unsupported map key type 0x%02x", c)
+}
+
+// readMsgPackBytes reads a length of size bytes and the data it counts
+func readMsgPackBytes(in *binaryInput, size int) ([]byte, error) {
+
+	n, err := in.uint(size)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return in.bytes(n)
+}
+
+// readMsgPackInt reads a signed big-endian integer of size bytes
+func readMsgPackInt(in *binaryInput, size int) (int64, error) {
+
+	v, err := in.uint(size)
+
+	if err != nil {
+		return 0, err
+	}
+
+	// sign extend from the top bit of the value
+	shift := 64 - 8*size
+
+	return int64(v<<shift) >> shift, nil
+}
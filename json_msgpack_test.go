@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONToMsgPack(t *testing.T) {
+	tests := []struct {
+		json    string
+		msgpack string
+	}{
+		{json: `0`, msgpack: "00"},
+		{json: `127`, msgpack: "7f"},
+		{json: `128`, msgpack: "cc80"},
+		{json: `256`, msgpack: "cd0100"},
+		{json: `65536`, msgpack: "ce00010000"},
+		{json: `4294967296`, msgpack: "cf0000000100000000"},
+		{json: `18446744073709551615`, msgpack: "cfffffffffffffffff"},
+		{json: `-1`, msgpack: "ff"},
+		{json: `-32`, msgpack: "e0"},
+		{json: `-33`, msgpack: "d0df"},
+		{json: `-129`, msgpack: "d1ff7f"},
+		{json: `-32769`, msgpack: "d2ffff7fff"},
+		{json: `-2147483649`, msgpack: "d3ffffffff7fffffff"},
+		{json: `1.5`, msgpack: "ca3fc00000"},
+		{json: `0.1`, msgpack: "cb3fb999999999999a"},
+		{json: `null`, msgpack: "c0"},
+		{json: `false`, msgpack: "c2"},
+		{json: `true`, msgpack: "c3"},
+		{json: `"a"`, msgpack: "a161"},
+		{json: `"` + strings.Repeat("x", 32) + `"`, msgpack: "d920" + strings.Repeat("78", 32)},
+		{json: `[1, 2]`, msgpack: "920102"},
+		{json: `[0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]`, msgpack: "dc0010" + strings.Repeat("00", 16)},
+		{json: `{"a": 1}`, msgpack: "81a16101"},
+	}
+
+	for _, tt := range tests {
+		got, err := JSONToMsgPack([]byte(tt.json))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.json, err)
+		}
+		if hex.EncodeToString(got) != tt.msgpack {
+			t.Errorf("%s: expected %s, got %x", tt.json, tt.msgpack, got)
+		}
+	}
+}
+
+func TestMsgPackToJSON(t *testing.T) {
+	tests := []struct {
+		msgpack string
+		json    string
+	}{
+		{msgpack: "c403010203", json: `"AQID"`},
+		{msgpack: "810102", json: `{"1":2}`},
+		{msgpack: "82d0df01d3ffffffffffffffff02", json: `{"-33":1,"-1":2}`},
+		{msgpack: "dd00000001da0002c3bc", json: `["ü"]`},
+		{msgpack: "de0000", json: `{}`},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.msgpack)
+		got, err := MsgPackToJSON(data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.msgpack, err)
+		}
+		if string(got) != tt.json {
+			t.Errorf("%s: expected %s, got %s", tt.msgpack, tt.json, got)
+		}
+	}
+
+	for _, bad := range []string{"", "c1", "d401ff", "cd01", "a261", "0000", "8190c0", "ca7fc00000", "cb7ff0000000000000", "cbfff0000000000000"} {
+		data, _ := hex.DecodeString(bad)
+		if _, err := MsgPackToJSON(data); !errors.Is(err, ErrMsgPackInvalid) {
+			t.Errorf("%s: expected ErrMsgPackInvalid, got %v", bad, err)
+		}
+	}
+}
+
+func TestMsgPackRoundTrip(t *testing.T) {
+	doc := `{"i":-9223372036854775808,"u":18446744073709551615,"f":0.1,"s":"x\"y","n":null,"a":[true,false,1.5,{}]}`
+
+	data, err := JSONToMsgPack([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := MsgPackToJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got) != doc {
+		t.Errorf("expected %s, got %s", doc, got)
+	}
+}
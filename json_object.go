@@ -0,0 +1,88 @@
+package utils
+
+// ReadObject reads a JSON object calling fn with each key, the lexer positioned at its value
+// fn decodes the value with a Read method or SkipValue, a value fn does not read is skipped
+// fn may return ErrJSONStop to end early, leaving the lexer inside the object
+func (l *JSONLexer) ReadObject(fn func(key string) error) error {
+	return readJSONMembers(l, fn)
+}
+
+// ReadObject reads a JSON object calling fn with each key, the reader positioned at its value
+// fn decodes the value with a Read method or SkipValue, a value fn does not read is skipped
+// Only the token being read is buffered, so large objects are decoded without holding them in memory
+// fn may return ErrJSONStop to end early, leaving the reader inside the object
+func (r *JSONReader) ReadObject(fn func(key string) error) error {
+	return readJSONMembers(r, fn)
+}
+
+// inputOffset returns the offset of the lexer in the whole input
+func (l *JSONLexer) inputOffset() int {
+	return l.base + l.pos
+}
+
+// inputOffset returns the offset of the reader in the whole input
+func (r *JSONReader) inputOffset() int {
+	return r.offset + r.pos
+}
+
+// isLenient reports whether the lexer accepts JSONC/JSON5 style input
+func (l *JSONLexer) isLenient() bool {
+	return l.lenient
+}
+
+// isLenient reports whether the reader accepts JSONC/JSON5 style input, which it never does
+func (r *JSONReader) isLenient() bool {
+	return false
+}
+
+// readJSONMembers reads an object from s calling fn for each member
+func readJSONMembers(s jsonValueScanner, fn func(key string) error) error {
+
+	if err := s.Expect(_BraceLeft); err != nil {
+		return err
+	}
+
+	s.SkipWhitespace()
+
+	if s.Peek() == _BraceRight {
+		s.Advance()
+		return nil
+	}
+
+	for {
+		key, err := s.ReadKey()
+
+		if err != nil {
+			return err
+		}
+
+		if err := s.Expect(_ColonChar); err != nil {
+			return err
+		}
+
+		s.SkipWhitespace()
+
+		start := s.inputOffset()
+
+		if err := fn(key); err != nil {
+			return jsonStopped(err)
+		}
+
+		if s.inputOffset() == start {
+			if err := s.SkipValue(); err != nil {
+				return err
+			}
+		}
+
+		s.SkipWhitespace()
+
+		if s.Peek() == _BraceRight {
+			s.Advance()
+			return nil
+		}
+
+		if err := s.Expect(_CommaChar); err != nil {
+			return err
+		}
+	}
+}
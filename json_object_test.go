@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadObject(t *testing.T) {
+	input := `{"id": 7, "skip": {"deep": [1, {"x": "}"}]}, "name": "bob", "tags": ["a", "b"], "last": null}`
+
+	type record struct {
+		ID   int
+		Name string
+		Tags []string
+		Keys []string
+	}
+
+	want := record{ID: 7, Name: "bob", Tags: []string{"a", "b"}, Keys: []string{"id", "skip", "name", "tags", "last"}}
+
+	decode := func(s JSONScanner) (record, error) {
+		var rec record
+		err := s.ReadObject(func(key string) error {
+			rec.Keys = append(rec.Keys, key)
+			var err error
+			switch key {
+			case "id":
+				rec.ID, err = s.ReadInt()
+			case "name":
+				rec.Name, err = s.ReadString()
+			case "tags":
+				rec.Tags, err = s.ReadArrayString()
+			}
+			return err
+		})
+		return rec, err
+	}
+
+	l := CreateJSONLexer([]byte(input))
+	defer ReleaseJSONLexer(l)
+
+	r := CreateJSONReader(iotest.OneByteReader(strings.NewReader(input)))
+	defer ReleaseJSONReader(r)
+
+	for _, s := range []JSONScanner{l, r} {
+		got, err := decode(s)
+		if err != nil {
+			t.Fatalf("%T: %v", s, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%T: expected %+v, got %+v", s, want, got)
+		}
+	}
+
+	var keys []string
+	err := CreateJSONLexer([]byte(`{"a": 1, "b": 2}`)).ReadObject(func(key string) error {
+		keys = append(keys, key)
+		return ErrJSONStop
+	})
+	if err != nil || len(keys) != 1 {
+		t.Errorf("expected stop after one key, got %v %v", keys, err)
+	}
+
+	// a wrapped ErrJSONStop also ends the iteration
+	err = CreateJSONLexer([]byte(`{"a": 1, "b": 2}`)).ReadObject(func(key string) error {
+		return fmt.Errorf("%s: %w", key, ErrJSONStop)
+	})
+	if err != nil {
+		t.Errorf("expected wrapped stop to end without error, got %v", err)
+	}
+
+	for _, bad := range []string{`[1]`, `{"a" 1}`, `{"a": 1 "b": 2}`, `{"a": }`, `{"a": 1`} {
+		var syntaxErr *JSONSyntaxError
+		err := CreateJSONLexer([]byte(bad)).ReadObject(func(string) error { return nil })
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%s: expected *JSONSyntaxError, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,648 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+)
+
+// JSONPatchOperation is one operation of an RFC 6902 JSON Patch
+// Value is used by add, replace and test, From by move and copy
+type JSONPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from"`
+	Value any    `json:"value"`
+}
+
+// MarshalJSON encodes the members the op uses, value is kept when it is null
+func (op JSONPatchOperation) MarshalJSON() ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	for i, name := range []string{"op", "path", "from", "value"} {
+
+		var v any
+
+		switch name {
+		case "op":
+			v = op.Op
+		case "path":
+			v = op.Path
+		case "from":
+			if op.Op != "move" && op.Op != "copy" {
+				continue
+			}
+			v = op.From
+		case "value":
+			if op.Op != "add" && op.Op != "replace" && op.Op != "test" {
+				continue
+			}
+			v = op.Value
+		}
+
+		value, err := encodeJSONValue(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			buf.WriteByte(_BraceLeft)
+		} else {
+			buf.WriteByte(_CommaChar)
+		}
+
+		buf.WriteString(`"` + name + `":`)
+		buf.Write(value)
+	}
+
+	buf.WriteByte(_BraceRight)
+
+	return buf.Bytes(), nil
+}
+
+// JSONPatch is an RFC 6902 JSON Patch document
+type JSONPatch []JSONPatchOperation
+
+// ParseJSONPatch decodes and checks a JSON Patch document
+// Numbers in values are kept as json.Number
+func ParseJSONPatch(data []byte) (JSONPatch, error) {
+
+	var raw []map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	patch := make(JSONPatch, len(raw))
+
+	for i, members := range raw {
+
+		op := &patch[i]
+
+		for _, name := range []string{"op", "path", "from"} {
+
+			value, ok := members[name]
+
+			if !ok {
+				continue
+			}
+
+			var s string
+
+			if err := json.Unmarshal(value, &s); err != nil {
+				return nil, fmt.Errorf("json patch operation %d: %s: %w", i, name, ErrJSONPatchInvalid)
+			}
+
+			switch name {
+			case "op":
+				op.Op = s
+			case "path":
+				op.Path = s
+			case "from":
+				op.From = s
+			}
+		}
+
+		if _, ok := members["path"]; !ok {
+			return nil, fmt.Errorf("json patch operation %d: missing path: %w", i, ErrJSONPatchInvalid)
+		}
+
+		switch op.Op {
+		case "add", "replace", "test":
+			value, ok := members["value"]
+			if !ok {
+				return nil, fmt.Errorf("json patch operation %d: missing value: %w", i, ErrJSONPatchInvalid)
+			}
+			v, err := decodeJSONValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("json patch operation %d: %w", i, err)
+			}
+			op.Value = v
+		case "move", "copy":
+			if _, ok := members["from"]; !ok {
+				return nil, fmt.Errorf("json patch operation %d: missing from: %w", i, ErrJSONPatchInvalid)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("json patch operation %d: op %q: %w", i, op.Op, ErrJSONPatchInvalid)
+		}
+	}
+
+	return patch, nil
+}
+
+// Apply applies the patch to a copy of doc and returns the result
+// The operations are all or nothing, on error doc is left unchanged
+func (p JSONPatch) Apply(doc any) (any, error) {
+
+	doc = jsonClone(doc)
+
+	for i, op := range p {
+
+		var err error
+
+		if doc, err = op.apply(doc); err != nil {
+			return nil, fmt.Errorf("json patch operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+// Fields returns the sorted top-level members the patch touches, for checking with CheckKeys
+// An operation on the whole document is reported as "*"
+func (p JSONPatch) Fields() []string {
+
+	fields := []string{}
+
+	for _, op := range p {
+
+		fields = appendJSONPointerField(fields, op.Path)
+
+		if op.Op == "move" {
+			fields = appendJSONPointerField(fields, op.From)
+		}
+	}
+
+	return sortFields(fields)
+}
+
+// apply applies a single operation to doc
+func (op *JSONPatchOperation) apply(doc any) (any, error) {
+
+	path, err := ParseJSONPointer(op.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		return jsonPatchAdd(doc, path, jsonClone(op.Value))
+	case "remove":
+		doc, _, err = jsonPatchRemove(doc, path)
+		return doc, err
+	case "replace":
+		if len(path) == 0 {
+			return jsonClone(op.Value), nil
+		}
+		return jsonPatchUpdate(doc, path, func(container any, token string) (any, error) {
+			return jsonPatchSet(container, token, jsonClone(op.Value))
+		})
+	case "test":
+		value, err := jsonPatchGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(value, op.Value) {
+			return nil, ErrJSONPatchTestFailed
+		}
+		return doc, nil
+	case "move", "copy":
+		from, err := ParseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value any
+		if op.Op == "move" {
+			if isJSONPointerPrefix(from, path) && len(from) < len(path) {
+				return nil, ErrJSONPatchInvalid
+			}
+			if doc, value, err = jsonPatchRemove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if value, err = jsonPatchGet(doc, from); err != nil {
+				return nil, err
+			}
+			value = jsonClone(value)
+		}
+		return jsonPatchAdd(doc, path, value)
+	default:
+		return nil, ErrJSONPatchInvalid
+	}
+}
+
+// ApplyJSONPatch applies the JSON Patch document patch to the JSON document doc
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+
+	p, err := ParseJSONPatch(patch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := decodeJSONValue(doc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if v, err = p.Apply(v); err != nil {
+		return nil, err
+	}
+
+	return encodeJSONValue(v)
+}
+
+// MergePatch applies an RFC 7396 merge patch to doc and returns the result
+// doc is not modified, null members of patch remove the member from doc
+func MergePatch(doc, patch any) any {
+
+	members, ok := patch.(map[string]any)
+
+	if !ok {
+		return jsonClone(patch)
+	}
+
+	target, ok := doc.(map[string]any)
+
+	result := make(map[string]any, len(target)+len(members))
+
+	if ok {
+		for k, v := range target {
+			result[k] = v
+		}
+	}
+
+	for k, v := range members {
+		if v == nil {
+			delete(result, k)
+		} else {
+			result[k] = MergePatch(result[k], v)
+		}
+	}
+
+	return result
+}
+
+// MergePatchBytes applies the merge patch document patch to the JSON document doc
+func MergePatchBytes(doc, patch []byte) ([]byte, error) {
+
+	p, err := decodeJSONValue(patch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := decodeJSONValue(doc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeJSONValue(MergePatch(v, p))
+}
+
+// MergePatchFields returns the sorted top-level members a merge patch touches, for checking with CheckKeys
+// A patch that is not an object replaces the whole document and is reported as "*"
+func MergePatchFields(patch any) []string {
+
+	members, ok := patch.(map[string]any)
+
+	if !ok {
+		return []string{"*"}
+	}
+
+	fields := make([]string, 0, len(members))
+
+	for k := range members {
+		fields = append(fields, k)
+	}
+
+	sort.Strings(fields)
+
+	return fields
+}
+
+// jsonPatchUpdate calls leaf with the container of the value at path and its last token
+// containers are replaced by what leaf returns, so slices can grow and shrink
+func jsonPatchUpdate(node any, path []string, leaf func(container any, token string) (any, error)) (any, error) {
+
+	if len(path) == 1 {
+		return leaf(node, path[0])
+	}
+
+	child, err := jsonPatchChild(node, path[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if child, err = jsonPatchUpdate(child, path[1:], leaf); err != nil {
+		return nil, err
+	}
+
+	return jsonPatchSet(node, path[0], child)
+}
+
+// jsonPatchAdd adds value at path
+func jsonPatchAdd(doc any, path []string, value any) (any, error) {
+
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return jsonPatchUpdate(doc, path, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[token] = value
+			return c, nil
+		case []any:
+			index := len(c)
+			if token != "-" {
+				i, err := parseJSONPointerIndex(token)
+				if err != nil {
+					return nil, err
+				}
+				if i > len(c) {
+					return nil, ErrJSONPointerNotFound
+				}
+				index = i
+			}
+			c = append(c, nil)
+			copy(c[index+1:], c[index:])
+			c[index] = value
+			return c, nil
+		default:
+			return nil, ErrJSONPointerNotFound
+		}
+	})
+}
+
+// jsonPatchRemove removes the value at path and returns it
+func jsonPatchRemove(doc any, path []string) (any, any, error) {
+
+	if len(path) == 0 {
+		return nil, nil, ErrJSONPatchInvalid
+	}
+
+	var removed any
+
+	doc, err := jsonPatchUpdate(doc, path, func(container any, token string) (any, error) {
+
+		value, err := jsonPatchChild(container, token)
+
+		if err != nil {
+			return nil, err
+		}
+
+		removed = value
+
+		switch c := container.(type) {
+		case map[string]any:
+			delete(c, token)
+			return c, nil
+		default:
+			s := container.([]any)
+			index, _ := parseJSONPointerIndex(token)
+			return append(s[:index], s[index+1:]...), nil
+		}
+	})
+
+	return doc, removed, err
+}
+
+// jsonPatchGet returns the value at path
+func jsonPatchGet(doc any, path []string) (any, error) {
+
+	for _, token := range path {
+
+		var err error
+
+		if doc, err = jsonPatchChild(doc, token); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+// jsonPatchChild returns the existing member or element token of node
+func jsonPatchChild(node any, token string) (any, error) {
+
+	switch c := node.(type) {
+	case map[string]any:
+		value, ok := c[token]
+		if !ok {
+			return nil, ErrJSONPointerNotFound
+		}
+		return value, nil
+	case []any:
+		index, err := parseJSONPointerIndex(token)
+		if err != nil {
+			return nil, err
+		}
+		if index >= len(c) {
+			return nil, ErrJSONPointerNotFound
+		}
+		return c[index], nil
+	default:
+		return nil, ErrJSONPointerNotFound
+	}
+}
+
+// jsonPatchSet replaces the existing member or element token of node with value
+func jsonPatchSet(node any, token string, value any) (any, error) {
+
+	if _, err := jsonPatchChild(node, token); err != nil {
+		return nil, err
+	}
+
+	switch c := node.(type) {
+	case map[string]any:
+		c[token] = value
+	case []any:
+		index, _ := parseJSONPointerIndex(token)
+		c[index] = value
+	}
+
+	return node, nil
+}
+
+// isJSONPointerPrefix reports whether prefix is a prefix of path
+func isJSONPointerPrefix(prefix, path []string) bool {
+
+	if len(prefix) > len(path) {
+		return false
+	}
+
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// appendJSONPointerField appends the top-level member of pointer to fields
+func appendJSONPointerField(fields []string, pointer string) []string {
+
+	tokens, err := ParseJSONPointer(pointer)
+
+	if err != nil || len(tokens) == 0 {
+		return append(fields, "*")
+	}
+
+	return append(fields, tokens[0])
+}
+
+// sortFields sorts fields and removes duplicates
+func sortFields(fields []string) []string {
+
+	sort.Strings(fields)
+
+	n := 0
+
+	for i, field := range fields {
+		if i == 0 || field != fields[n-1] {
+			fields[n] = field
+			n++
+		}
+	}
+
+	return fields[:n]
+}
+
+// jsonClone deep copies the objects and arrays of v
+func jsonClone(v any) any {
+
+	switch c := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(c))
+		for k, v := range c {
+			m[k] = jsonClone(v)
+		}
+		return m
+	case []any:
+		s := make([]any, len(c))
+		for i, v := range c {
+			s[i] = jsonClone(v)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// jsonEqual reports whether a and b are the same JSON value
+// numbers are compared by value whatever their Go type
+func jsonEqual(a, b any) bool {
+
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !jsonEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case nil, bool, string:
+		return a == b
+	}
+
+	x, ok := jsonRat(a)
+
+	if !ok {
+		return false
+	}
+
+	y, ok := jsonRat(b)
+
+	return ok && x.Cmp(y) == 0
+}
+
+// jsonRat returns the exact value of a number
+func jsonRat(v any) (*big.Rat, bool) {
+
+	r := new(big.Rat)
+
+	switch n := v.(type) {
+	case json.Number:
+		return r.SetString(string(n))
+	case float64:
+		r = r.SetFloat64(n)
+		return r, r != nil
+	case float32:
+		r = r.SetFloat64(float64(n))
+		return r, r != nil
+	case int:
+		return r.SetInt64(int64(n)), true
+	case int8:
+		return r.SetInt64(int64(n)), true
+	case int16:
+		return r.SetInt64(int64(n)), true
+	case int32:
+		return r.SetInt64(int64(n)), true
+	case int64:
+		return r.SetInt64(n), true
+	case uint:
+		return r.SetUint64(uint64(n)), true
+	case uint8:
+		return r.SetUint64(uint64(n)), true
+	case uint16:
+		return r.SetUint64(uint64(n)), true
+	case uint32:
+		return r.SetUint64(uint64(n)), true
+	case uint64:
+		return r.SetUint64(n), true
+	default:
+		return nil, false
+	}
+}
+
+// decodeJSONValue decodes a single JSON value keeping numbers as json.Number
+func decodeJSONValue(data []byte) (any, error) {
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after value at offset %d", dec.InputOffset())
+	}
+
+	return v, nil
+}
+
+// encodeJSONValue encodes v without escaping HTML characters
+func encodeJSONValue(v any) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
@@ -0,0 +1,198 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMergePatchBytes(t *testing.T) {
+	// examples from RFC 7396 appendix A
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{doc: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{doc: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{doc: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{doc: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{doc: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{doc: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{doc: `{"e":null}`, patch: `{"a":1}`, want: `{"a":1,"e":null}`},
+		{doc: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+		{doc: `{"n":12345678901234567890}`, patch: `{"m":1.50}`, want: `{"m":1.50,"n":12345678901234567890}`},
+	}
+
+	for _, tt := range tests {
+		got, err := MergePatchBytes([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("%s + %s: unexpected error: %v", tt.doc, tt.patch, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s + %s: expected %s, got %s", tt.doc, tt.patch, tt.want, got)
+		}
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{name: "add member", doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz","value":"qux"}]`, want: `{"baz":"qux","foo":"bar"}`},
+		{name: "add element", doc: `{"foo":["bar","baz"]}`, patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`, want: `{"foo":["bar","qux","baz"]}`},
+		{name: "add end", doc: `{"foo":[1]}`, patch: `[{"op":"add","path":"/foo/-","value":[2]}]`, want: `{"foo":[1,[2]]}`},
+		{name: "add null", doc: `{}`, patch: `[{"op":"add","path":"/a","value":null}]`, want: `{"a":null}`},
+		{name: "remove member", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`, want: `{"foo":"bar"}`},
+		{name: "remove element", doc: `{"foo":["bar","qux","baz"]}`, patch: `[{"op":"remove","path":"/foo/1"}]`, want: `{"foo":["bar","baz"]}`},
+		{name: "replace", doc: `{"baz":"qux","foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":"boo"}]`, want: `{"baz":"boo","foo":"bar"}`},
+		{name: "replace root", doc: `{"a":1}`, patch: `[{"op":"replace","path":"","value":[1]}]`, want: `[1]`},
+		{name: "move", doc: `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, want: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{name: "move element", doc: `{"foo":["all","grass","cows","eat"]}`, patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, want: `{"foo":["all","cows","eat","grass"]}`},
+		{name: "copy", doc: `{"a":{"b":1}}`, patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, want: `{"a":{"b":1},"c":{"b":2}}`},
+		{name: "test", doc: `{"baz":"qux","foo":["a",2,"c"]}`, patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, want: `{"baz":"qux","foo":["a",2,"c"]}`},
+		{name: "escaped", doc: `{"a/b":1,"m~n":2}`, patch: `[{"op":"remove","path":"/a~1b"},{"op":"replace","path":"/m~0n","value":3}]`, want: `{"m~n":3}`},
+		{name: "test failed", doc: `{"baz":"qux"}`, patch: `[{"op":"test","path":"/baz","value":"bar"}]`, wantErr: ErrJSONPatchTestFailed},
+		{name: "missing target", doc: `{"baz":"qux"}`, patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`, wantErr: ErrJSONPointerNotFound},
+		{name: "out of bounds", doc: `{"foo":[1]}`, patch: `[{"op":"add","path":"/foo/2","value":2}]`, wantErr: ErrJSONPointerNotFound},
+		{name: "remove missing", doc: `{}`, patch: `[{"op":"remove","path":"/a"}]`, wantErr: ErrJSONPointerNotFound},
+		{name: "move into child", doc: `{"a":{"b":1}}`, patch: `[{"op":"move","from":"/a","path":"/a/c"}]`, wantErr: ErrJSONPatchInvalid},
+		{name: "missing value", doc: `{}`, patch: `[{"op":"add","path":"/a"}]`, wantErr: ErrJSONPatchInvalid},
+		{name: "unknown op", doc: `{}`, patch: `[{"op":"merge","path":"/a","value":1}]`, wantErr: ErrJSONPatchInvalid},
+		{name: "leading zero", doc: `{"foo":[1,2]}`, patch: `[{"op":"remove","path":"/foo/01"}]`, wantErr: ErrJSONPointerInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyJSONPatch([]byte(tt.doc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJSONPatchAtomic(t *testing.T) {
+	doc := map[string]any{"a": []any{1.0, 2.0}, "b": map[string]any{"c": "d"}}
+
+	patch := JSONPatch{
+		{Op: "remove", Path: "/a/0"},
+		{Op: "add", Path: "/b/e", Value: "f"},
+		{Op: "test", Path: "/b/c", Value: "x"},
+	}
+
+	if _, err := patch.Apply(doc); !errors.Is(err, ErrJSONPatchTestFailed) {
+		t.Fatalf("expected test failure, got %v", err)
+	}
+
+	want := map[string]any{"a": []any{1.0, 2.0}, "b": map[string]any{"c": "d"}}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("document changed by failed patch: %v", doc)
+	}
+}
+
+func TestJSONPatchFields(t *testing.T) {
+	patch, err := ParseJSONPatch([]byte(`[
+		{"op": "replace", "path": "/name", "value": "x"},
+		{"op": "add", "path": "/tags/-", "value": "y"},
+		{"op": "move", "from": "/email", "path": "/name"},
+		{"op": "test", "path": "/tags/0", "value": "z"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := patch.Fields(); !reflect.DeepEqual(got, []string{"email", "name", "tags"}) {
+		t.Errorf("expected [email name tags], got %v", got)
+	}
+
+	if got := (JSONPatch{{Op: "replace", Path: "", Value: 1}}).Fields(); !reflect.DeepEqual(got, []string{"*"}) {
+		t.Errorf("expected [*], got %v", got)
+	}
+
+	if got := MergePatchFields(map[string]any{"b": nil, "a": 1}); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("expected [a b], got %v", got)
+	}
+}
+
+func TestJSONPatchMarshal(t *testing.T) {
+	patch := JSONPatch{
+		{Op: "add", Path: "/a", Value: nil},
+		{Op: "replace", Path: "/b", Value: nil, From: "/x"},
+		{Op: "test", Path: "/c", Value: "v"},
+		{Op: "remove", Path: "/d", Value: "ignored"},
+		{Op: "move", From: "", Path: "/e"},
+		{Op: "copy", From: "/f", Path: "/g"},
+	}
+
+	got, err := json.Marshal(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[{"op":"add","path":"/a","value":null},{"op":"replace","path":"/b","value":null},` +
+		`{"op":"test","path":"/c","value":"v"},{"op":"remove","path":"/d"},` +
+		`{"op":"move","path":"/e","from":""},{"op":"copy","path":"/g","from":"/f"}]`
+
+	if string(got) != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	// the changes of a diff to null survive a round trip through JSON
+	a, b := []byte(`{"a": 1}`), []byte(`{"a": null, "b": null}`)
+
+	changes, err := JSONDiff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := changes.Patch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseJSONPatch(data)
+	if err != nil {
+		t.Fatalf("%s: %v", data, err)
+	}
+
+	doc, err := decodeJSONValue(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := parsed.Apply(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]any{"a": nil, "b": nil}; !reflect.DeepEqual(result, want) {
+		t.Errorf("expected %v, got %v", want, result)
+	}
+}
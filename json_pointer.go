@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"strings"
+)
+
+// ParseJSONPointer splits an RFC 6901 JSON Pointer into unescaped reference tokens
+// The empty pointer refers to the whole document and returns no tokens
+func ParseJSONPointer(pointer string) ([]string, error) {
+
+	if pointer == "" {
+		return []string{}, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, ErrJSONPointerInvalid
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+
+	for i, token := range tokens {
+
+		if strings.IndexByte(token, '~') < 0 {
+			continue
+		}
+
+		var sb strings.Builder
+
+		for j := 0; j < len(token); j++ {
+
+			if token[j] != '~' {
+				sb.WriteByte(token[j])
+				continue
+			}
+
+			if j+1 >= len(token) {
+				return nil, ErrJSONPointerInvalid
+			}
+
+			j++
+
+			switch token[j] {
+			case '0':
+				sb.WriteByte('~')
+			case '1':
+				sb.WriteByte('/')
+			default:
+				return nil, ErrJSONPointerInvalid
+			}
+		}
+
+		tokens[i] = sb.String()
+	}
+
+	return tokens, nil
+}
+
+// EscapeJSONPointer escapes a reference token, '~' becomes "~0" and '/' becomes "~1"
+func EscapeJSONPointer(token string) string {
+
+	if strings.IndexByte(token, '~') < 0 && strings.IndexByte(token, '/') < 0 {
+		return token
+	}
+
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// JoinJSONPointer builds a JSON Pointer from unescaped reference tokens
+func JoinJSONPointer(tokens ...string) string {
+
+	var sb strings.Builder
+
+	for _, token := range tokens {
+		sb.WriteByte('/')
+		sb.WriteString(EscapeJSONPointer(token))
+	}
+
+	return sb.String()
+}
+
+// JSONPointerGet returns the raw bytes of the value at pointer in data
+// Only the path to the value is scanned, everything else is skipped without decoding
+// The returned slice refers to data
+func JSONPointerGet(data []byte, pointer string) ([]byte, error) {
+
+	tokens, err := ParseJSONPointer(pointer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	l := CreateJSONLexer(data)
+	defer ReleaseJSONLexer(l)
+
+	for _, token := range tokens {
+
+		l.SkipWhitespace()
+
+		switch l.Peek() {
+		case _BraceLeft:
+			err = jsonLexerMember(l, token)
+		case _BracketLeft:
+			err = jsonLexerElement(l, token)
+		default:
+			err = ErrJSONPointerNotFound
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return l.ReadRawValue()
+}
+
+// jsonLexerMember advances l from '{' to the value of key
+func jsonLexerMember(l *JSONLexer, key string) error {
+
+	l.Advance()
+	l.SkipWhitespace()
+
+	if l.Peek() == _BraceRight {
+		return ErrJSONPointerNotFound
+	}
+
+	for {
+		name, err := l.ReadString()
+
+		if err != nil {
+			return err
+		}
+
+		if err := l.Expect(_ColonChar); err != nil {
+			return err
+		}
+
+		if name == key {
+			return nil
+		}
+
+		if err := l.SkipValue(); err != nil {
+			return err
+		}
+
+		l.SkipWhitespace()
+
+		if l.Peek() == _BraceRight {
+			return ErrJSONPointerNotFound
+		}
+
+		if err := l.Expect(_CommaChar); err != nil {
+			return err
+		}
+	}
+}
+
+// jsonLexerElement advances l from '[' to the element at token
+func jsonLexerElement(l *JSONLexer, token string) error {
+
+	index, err := parseJSONPointerIndex(token)
+
+	if err != nil {
+		return err
+	}
+
+	l.Advance()
+	l.SkipWhitespace()
+
+	if l.Peek() == _BracketRight {
+		return ErrJSONPointerNotFound
+	}
+
+	for i := 0; i < index; i++ {
+
+		if err := l.SkipValue(); err != nil {
+			return err
+		}
+
+		l.SkipWhitespace()
+
+		if l.Peek() == _BracketRight {
+			return ErrJSONPointerNotFound
+		}
+
+		if err := l.Expect(_CommaChar); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseJSONPointerIndex parses an array index token
+// "-" refers past the last element and is reported as not found
+func parseJSONPointerIndex(token string) (int, error) {
+
+	if token == "-" {
+		return 0, ErrJSONPointerNotFound
+	}
+
+	if token == "" || (token[0] == '0' && len(token) > 1) {
+		return 0, ErrJSONPointerInvalid
+	}
+
+	index := 0
+
+	for i := 0; i < len(token); i++ {
+
+		if !isDigit(token[i]) || index > (1<<31)/10 {
+			return 0, ErrJSONPointerInvalid
+		}
+
+		index = index*10 + int(token[i]-'0')
+	}
+
+	return index, nil
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJSONPointerGet(t *testing.T) {
+	// example document from RFC 6901 section 5
+	doc := []byte(`{
+		"foo": ["bar", "baz"],
+		"": 0,
+		"a/b": 1,
+		"c%d": 2,
+		"e^f": 3,
+		"g|h": 4,
+		"i\\j": 5,
+		"k\"l": 6,
+		" ": 7,
+		"m~n": 8,
+		"deep": {"list": [{"x": 1}, {"x": [true, null]}]}
+	}`)
+
+	tests := []struct {
+		pointer string
+		want    string
+		wantErr error
+	}{
+		{pointer: "/foo", want: `["bar", "baz"]`},
+		{pointer: "/foo/0", want: `"bar"`},
+		{pointer: "/foo/1", want: `"baz"`},
+		{pointer: "/", want: `0`},
+		{pointer: "/a~1b", want: `1`},
+		{pointer: "/c%d", want: `2`},
+		{pointer: "/e^f", want: `3`},
+		{pointer: "/g|h", want: `4`},
+		{pointer: `/i\j`, want: `5`},
+		{pointer: `/k"l`, want: `6`},
+		{pointer: "/ ", want: `7`},
+		{pointer: "/m~0n", want: `8`},
+		{pointer: "/deep/list/1/x/0", want: `true`},
+		{pointer: "/foo/2", wantErr: ErrJSONPointerNotFound},
+		{pointer: "/foo/-", wantErr: ErrJSONPointerNotFound},
+		{pointer: "/foo/01", wantErr: ErrJSONPointerInvalid},
+		{pointer: "/missing", wantErr: ErrJSONPointerNotFound},
+		{pointer: "/foo/0/x", wantErr: ErrJSONPointerNotFound},
+		{pointer: "/m~2n", wantErr: ErrJSONPointerInvalid},
+		{pointer: "foo", wantErr: ErrJSONPointerInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			got, err := JSONPointerGet(doc, tt.pointer)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJoinJSONPointer(t *testing.T) {
+	if got := JoinJSONPointer("a/b", "m~n", "0"); got != "/a~1b/m~0n/0" {
+		t.Errorf("expected /a~1b/m~0n/0, got %s", got)
+	}
+
+	tokens, err := ParseJSONPointer("/a~1b/m~0n/0")
+	if err != nil || len(tokens) != 3 || tokens[0] != "a/b" || tokens[1] != "m~n" {
+		t.Errorf("expected [a/b m~n 0], got %v %v", tokens, err)
+	}
+}
@@ -0,0 +1,709 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+const (
+	jsonReaderBufferSize = 4096
+	// buffers grown past this size are not kept in the pool
+	jsonReaderMaxPooledSize = 1 << 16
+)
+
+// JSONScanner is the method set shared by JSONLexer and JSONReader
+// Code written against it works on both []byte input and streams
+type JSONScanner interface {
+	Position() (int, int)
+	SetInvalidUTF8(mode JSONInvalidUTF8)
+	SetLimits(limits JSONLimits)
+	SetValueOptions(options JSONValueOptions)
+	SetStrict(strict bool)
+	Peek() byte
+	Advance()
+	SkipWhitespace()
+	Expect(c byte) error
+	ReadString() (string, error)
+	ReadStringBytes() ([]byte, error)
+	ReadKey() (string, error)
+	ReadKeyBytes() ([]byte, error)
+	ReadNumber() (float64, error)
+	ReadNumberRaw() ([]byte, error)
+	ReadJSONNumber() (json.Number, error)
+	ReadInt() (int, error)
+	ReadUint() (uint, error)
+	ReadInt64() (int64, error)
+	ReadUint64() (uint64, error)
+	ReadBool() (bool, error)
+	ReadNull() error
+	ReadArrayString() ([]string, error)
+	ReadArrayFloat64() ([]float64, error)
+	ReadRawValue() ([]byte, error)
+	ReadValue() (any, error)
+	ReadObject(fn func(key string) error) error
+	SkipValue() error
+	ExpectEOF() error
+	Next() (JSONToken, error)
+}
+
+var (
+	_ JSONScanner = (*JSONLexer)(nil)
+	_ JSONScanner = (*JSONReader)(nil)
+)
+
+var jsonReaderPool = sync.Pool{
+	New: func() any {
+		return &JSONReader{buf: make([]byte, jsonReaderBufferSize)}
+	},
+}
+
+// CreateJSONReader return *JSONReader reading from r
+func CreateJSONReader(r io.Reader) *JSONReader {
+	reader := jsonReaderPool.Get().(*JSONReader)
+	reader.reset(r)
+	return reader
+}
+
+// ReleaseJSONReader puts the reader back to the pool
+func ReleaseJSONReader(reader *JSONReader) {
+	reader.reset(nil)
+	if len(reader.buf) > jsonReaderMaxPooledSize {
+		reader.buf = make([]byte, jsonReaderBufferSize)
+	}
+	if cap(reader.lexer.scratch) > jsonReaderMaxPooledSize {
+		reader.lexer.scratch = nil
+	}
+	jsonReaderPool.Put(reader)
+}
+
+// JSONReader reads JSON from an io.Reader
+// It owns the buffer and tracks line and column, so callers do not thread buf, pos and n
+// Only the token being read is buffered, scalars are decoded by the same rules as JSONLexer
+// Slices returned by ReadNumberRaw, ReadRawValue and Next are only valid until the next call
+type JSONReader struct {
+	r      io.Reader
+	buf    []byte
+	pos    int
+	n      int
+	mark   int // start of bytes kept on refill, -1 if none
+	offset int // offset of buf[0] in the input
+	line   int
+	column int
+	err    error
+
+	lexer   JSONLexer
+	tok     jsonTokenizer
+	skipTok jsonTokenizer
+	limits  JSONLimits
+	tokens  int
+
+	invalidUTF8  JSONInvalidUTF8
+	valueOptions JSONValueOptions
+	strict       bool
+}
+
+// reset prepares the reader for r
+func (r *JSONReader) reset(rd io.Reader) {
+	r.r = rd
+	r.pos = 0
+	r.n = 0
+	r.mark = -1
+	r.offset = 0
+	r.line = 1
+	r.column = 1
+	r.err = nil
+	r.lexer.data = nil
+	r.lexer.len = 0
+	r.tok.reset()
+	r.limits = JSONLimits{}
+	r.tokens = 0
+	r.invalidUTF8 = JSONInvalidUTF8Replace
+	r.valueOptions = JSONValueOptions{}
+	r.strict = false
+}
+
+// Position returns current line and column for error reporting
+func (r *JSONReader) Position() (int, int) {
+	return r.line, r.column
+}
+
+// SetInvalidUTF8 sets how ReadString handles invalid UTF-8 and lone surrogates
+// The default is JSONInvalidUTF8Replace
+func (r *JSONReader) SetInvalidUTF8(mode JSONInvalidUTF8) {
+	r.invalidUTF8 = mode
+}
+
+// SetStrict makes numbers and literals follow RFC 8259 exactly, see JSONLexer.SetStrict
+func (r *JSONReader) SetStrict(strict bool) {
+	r.strict = strict
+}
+
+// fill reads more input into the buffer
+// Bytes before pos are dropped unless a mark keeps them, the buffer grows only when it is full
+// It returns false at EOF or on a read error
+func (r *JSONReader) fill() bool {
+	if r.err != nil {
+		return false
+	}
+
+	keep := r.pos
+	if r.mark >= 0 && r.mark < keep {
+		keep = r.mark
+	}
+	if keep > 0 {
+		copy(r.buf, r.buf[keep:r.n])
+		r.n -= keep
+		r.pos -= keep
+		r.offset += keep
+		if r.mark >= 0 {
+			r.mark -= keep
+		}
+	}
+
+	if r.n == len(r.buf) {
+		buf := make([]byte, 2*len(r.buf))
+		copy(buf, r.buf[:r.n])
+		r.buf = buf
+	}
+
+	for i := 0; i < 100; i++ {
+		m, err := r.r.Read(r.buf[r.n:])
+		r.n += m
+		if err != nil {
+			r.err = err
+			return m > 0
+		}
+		if m > 0 {
+			return true
+		}
+	}
+
+	r.err = io.ErrNoProgress
+	return false
+}
+
+// readErr returns the read error that stopped the input, nil at EOF
+func (r *JSONReader) readErr() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
+
+// Peek returns the next byte in the input without advancing the reader
+// If the reader is at the end of the input, it returns 0
+func (r *JSONReader) Peek() byte {
+	if r.pos >= r.n && !r.fill() {
+		return 0
+	}
+	return r.buf[r.pos]
+}
+
+// Advance moves the reader to the next byte in the input
+// If the reader is at the end of the input, it does nothing
+func (r *JSONReader) Advance() {
+	if r.pos >= r.n && !r.fill() {
+		return
+	}
+	if r.buf[r.pos] == '\n' {
+		r.line++
+		r.column = 1
+	} else {
+		r.column++
+	}
+	r.pos++
+}
+
+// SkipWhitespace advances the reader until it reaches a non-whitespace character
+func (r *JSONReader) SkipWhitespace() {
+	for {
+		switch r.Peek() {
+		case ' ', '\r', '\n', '\t':
+			r.Advance()
+		default:
+			return
+		}
+	}
+}
+
+// Expect checks if the next byte in the input is equal to the given byte
+// If it is, the reader advances to the next byte
+// If it is not, an error is returned
+func (r *JSONReader) Expect(c byte) error {
+	r.SkipWhitespace()
+	if r.pos >= r.n {
+		if err := r.readErr(); err != nil {
+			return err
+		}
+		return r.syntaxError(jsonQuoteByte(c), "EOF")
+	}
+	if r.buf[r.pos] != c {
+		return r.syntaxError(jsonQuoteByte(c), jsonQuoteByte(r.buf[r.pos]))
+	}
+	r.Advance()
+	return nil
+}
+
+// bufferString makes sure the string starting at pos is in the buffer
+// and returns the index after its closing quote, or n if the input ends first
+// A string longer than MaxStringLength is rejected before it is buffered in full
+func (r *JSONReader) bufferString() (int, error) {
+	i := r.pos + 1
+	for {
+		if r.limits.MaxStringLength > 0 && i-r.pos-1 > r.limits.MaxStringLength {
+			return 0, r.syntaxErrorMsg("", ErrJSONStringTooLong)
+		}
+		for i < r.n {
+			switch r.buf[i] {
+			case '\\':
+				i += 2
+				continue
+			case _QuoteChar:
+				return i + 1, nil
+			}
+			i++
+		}
+		pos := r.pos
+		if !r.fill() {
+			return r.n, r.readErr()
+		}
+		i -= pos - r.pos
+	}
+}
+
+// bufferLiteral makes sure the number or literal starting at pos is in the buffer
+// and returns the index of the delimiter following it
+func (r *JSONReader) bufferLiteral() (int, error) {
+	i := r.pos
+	for {
+		for i < r.n {
+			switch r.buf[i] {
+			case ' ', '\r', '\n', '\t', _CommaChar, _ColonChar, _QuoteChar, _BracketLeft, _BracketRight, _BraceLeft, _BraceRight:
+				return i, nil
+			}
+			i++
+		}
+		pos := r.pos
+		if !r.fill() {
+			return r.n, r.readErr()
+		}
+		i -= pos - r.pos
+	}
+}
+
+// lex points the internal lexer at the buffered token ending at end
+func (r *JSONReader) lex(end int) *JSONLexer {
+	l := &r.lexer
+	l.data = r.buf[r.pos:end]
+	l.len = end - r.pos
+	l.pos = 0
+	l.base = r.offset + r.pos
+	l.limits = r.limits
+	l.tokens = r.tokens
+	l.line = r.line
+	l.column = r.column
+	l.invalidUTF8 = r.invalidUTF8
+	l.strict = r.strict
+	return l
+}
+
+// lexString buffers the next string and returns the lexer to decode it
+func (r *JSONReader) lexString() (*JSONLexer, error) {
+	r.SkipWhitespace()
+	if r.Peek() != _QuoteChar {
+		return nil, r.Expect(_QuoteChar)
+	}
+	end, err := r.bufferString()
+	if err != nil {
+		return nil, err
+	}
+	return r.lex(end), nil
+}
+
+// lexLiteral buffers the next number or literal and returns the lexer to decode it
+func (r *JSONReader) lexLiteral() (*JSONLexer, error) {
+	r.SkipWhitespace()
+	end, err := r.bufferLiteral()
+	if err != nil {
+		return nil, err
+	}
+	// the lexer also sees the delimiter, so strict mode checks it like for a whole document
+	if end < r.n {
+		end++
+	}
+	return r.lex(end), nil
+}
+
+// sync advances the reader past what the internal lexer consumed and returns err
+// the lexer only sees the buffered token, so its EOF may be a delimiter in the input
+func (r *JSONReader) sync(err error) error {
+	r.pos += r.lexer.pos
+	r.line = r.lexer.line
+	r.column = r.lexer.column
+	r.tokens = r.lexer.tokens
+	if e, ok := err.(*JSONSyntaxError); ok && e.Got == "EOF" && r.pos < r.n {
+		e.Got = jsonQuoteByte(r.buf[r.pos])
+	}
+	return err
+}
+
+// ReadString reads a JSON string from the input
+// and returns it as a string
+// The string may contain escaped characters
+func (r *JSONReader) ReadString() (string, error) {
+	l, err := r.lexString()
+	if err != nil {
+		return "", err
+	}
+	s, err := l.ReadString()
+	return s, r.sync(err)
+}
+
+// ReadStringBytes reads a JSON string like ReadString without allocating
+// The slice refers to the buffer of the reader and is only valid until the next call
+func (r *JSONReader) ReadStringBytes() ([]byte, error) {
+	l, err := r.lexString()
+	if err != nil {
+		return nil, err
+	}
+	b, err := l.ReadStringBytes()
+	return b, r.sync(err)
+}
+
+// ReadKey reads an object key
+// The reader has no lenient mode, so a key is always a JSON string
+func (r *JSONReader) ReadKey() (string, error) {
+	return r.ReadString()
+}
+
+// ReadKeyBytes reads an object key like ReadKey without allocating
+// The slice refers to the buffer of the reader and is only valid until the next call
+func (r *JSONReader) ReadKeyBytes() ([]byte, error) {
+	return r.ReadStringBytes()
+}
+
+// ReadNumber reads a JSON number from the input
+// and returns it as a float64
+func (r *JSONReader) ReadNumber() (float64, error) {
+	l, err := r.lexLiteral()
+	if err != nil {
+		return 0, err
+	}
+	val, err := l.ReadNumber()
+	return val, r.sync(err)
+}
+
+// ReadNumberRaw reads a JSON number from the input
+// and returns its literal bytes without converting them
+func (r *JSONReader) ReadNumberRaw() ([]byte, error) {
+	l, err := r.lexLiteral()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := l.ReadNumberRaw()
+	return raw, r.sync(err)
+}
+
+// ReadJSONNumber reads a JSON number from the input
+// and returns its literal text as a json.Number
+func (r *JSONReader) ReadJSONNumber() (json.Number, error) {
+	l, err := r.lexLiteral()
+	if err != nil {
+		return "", err
+	}
+	val, err := l.ReadJSONNumber()
+	return val, r.sync(err)
+}
+
+// ReadInt reads a JSON number from the input
+// and returns it as an integer
+func (r *JSONReader) ReadInt() (int, error) {
+	l, err := r.lexLiteral()
+	if err != nil {
+		return 0, err
+	}
+	val, err := l.ReadInt()
+	return val, r.sync(err)
+}
+
+// ReadUint reads a JSON number as an unsigned integer, rejecting negatives and floats
+func (r *JSONReader) ReadUint() (uint, error) {
+	l, err := r.lexLiteral()
+	if err != nil {
+		return 0, err
+	}
+	val, err := l.ReadUint()
+	return val, r.sync(err)
+}
+
+// ReadInt64 reads a JSON number from the input
+// and returns it as an int64
+func (r *JSONReader) ReadInt64() (int64, error) {
+	l, err := r.lexLiteral()
+	if err != nil {
+		return 0, err
+	}
+	val, err := l.ReadInt64()
+	return val, r.sync(err)
+}
+
+// ReadUint64 reads a JSON number from the input
+// and returns it as a uint64
+func (r *JSONReader) ReadUint64() (uint64, error) {
+	l, err := r.lexLiteral()
+	if err != nil {
+		return 0, err
+	}
+	val, err := l.ReadUint64()
+	return val, r.sync(err)
+}
+
+// ReadBool reads a JSON boolean from the input
+func (r *JSONReader) ReadBool() (bool, error) {
+	l, err := r.lexLiteral()
+	if err != nil {
+		return false, err
+	}
+	val, err := l.ReadBool()
+	return val, r.sync(err)
+}
+
+// ReadNull reads a JSON null value from the input
+func (r *JSONReader) ReadNull() error {
+	l, err := r.lexLiteral()
+	if err != nil {
+		return err
+	}
+	return r.sync(l.ReadNull())
+}
+
+// ReadArrayString reads a JSON array of strings
+func (r *JSONReader) ReadArrayString() ([]string, error) {
+	if err := r.Expect(_BracketLeft); err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+
+	r.SkipWhitespace()
+	if r.Peek() == _BracketRight {
+		r.Advance()
+		return result, nil
+	}
+
+	for {
+		val, err := r.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, val)
+
+		r.SkipWhitespace()
+		if r.Peek() == _BracketRight {
+			r.Advance()
+			break
+		}
+		if err := r.Expect(_CommaChar); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ReadArrayFloat64 reads a JSON array of floating point numbers
+func (r *JSONReader) ReadArrayFloat64() ([]float64, error) {
+	if err := r.Expect(_BracketLeft); err != nil {
+		return nil, err
+	}
+
+	result := make([]float64, 0)
+
+	r.SkipWhitespace()
+	if r.Peek() == _BracketRight {
+		r.Advance()
+		return result, nil
+	}
+
+	for {
+		val, err := r.ReadNumber()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, val)
+
+		r.SkipWhitespace()
+		if r.Peek() == _BracketRight {
+			r.Advance()
+			break
+		}
+		if err := r.Expect(_CommaChar); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ReadRawValue reads the next JSON value and returns its bytes undecoded
+// The value is buffered as a whole, the slice is valid until the next call
+func (r *JSONReader) ReadRawValue() ([]byte, error) {
+	r.SkipWhitespace()
+	r.mark = r.pos
+	err := r.SkipValue()
+	start := r.mark
+	r.mark = -1
+	if err != nil {
+		return nil, err
+	}
+	return r.buf[start:r.pos], nil
+}
+
+// SkipValue skips over a JSON value in the input
+// Containers are skipped token by token, so only one token is buffered at a time
+func (r *JSONReader) SkipValue() error {
+	r.skipTok.reset()
+	for {
+		if _, err := r.next(&r.skipTok, false); err != nil {
+			if err == io.EOF {
+				return r.syntaxError("value", "EOF")
+			}
+			return err
+		}
+		if r.skipTok.state == jsonStateDone {
+			return nil
+		}
+	}
+}
+
+// Next reads the next token of the document
+// It returns io.EOF after the last top-level value
+// Next keeps its own view of the document structure, so it should not be mixed with the Read methods
+func (r *JSONReader) Next() (JSONToken, error) {
+	return r.next(&r.tok, true)
+}
+
+// next reads the next token as tracked by t, decoding Key and String values if decode is set
+func (r *JSONReader) next(t *jsonTokenizer, decode bool) (JSONToken, error) {
+	for {
+		r.SkipWhitespace()
+		if r.pos >= r.n {
+			if err := r.readErr(); err != nil {
+				return JSONToken{}, err
+			}
+			if t.atEOF() {
+				return JSONToken{}, io.EOF
+			}
+			return JSONToken{}, r.syntaxError(t.expected(), "EOF")
+		}
+
+		kind, skip, expected := t.step(r.buf[r.pos])
+		if expected != "" {
+			return JSONToken{}, r.syntaxError(expected, jsonQuoteByte(r.buf[r.pos]))
+		}
+		if skip {
+			r.Advance()
+			continue
+		}
+
+		tok := JSONToken{Kind: kind, Offset: r.offset + r.pos, Line: r.line, Column: r.column}
+
+		var l *JSONLexer
+		var err error
+		switch kind {
+		case JSONTokenKey, JSONTokenString:
+			l, err = r.lexString()
+			if err == nil {
+				if decode {
+					tok.Value, err = l.ReadString()
+				} else {
+					err = l.SkipValue()
+				}
+			}
+		case JSONTokenNumber:
+			l, err = r.lexLiteral()
+			if err == nil {
+				_, err = l.ReadNumberRaw()
+			}
+		case JSONTokenBool:
+			l, err = r.lexLiteral()
+			if err == nil {
+				_, err = l.ReadBool()
+			}
+		case JSONTokenNull:
+			l, err = r.lexLiteral()
+			if err == nil {
+				err = l.ReadNull()
+			}
+		default:
+			if err := r.countToken(); err != nil {
+				return JSONToken{}, err
+			}
+			if kind == JSONTokenObjectStart || kind == JSONTokenArrayStart {
+				if err := r.checkDepth(len(t.stack)); err != nil {
+					return JSONToken{}, err
+				}
+			}
+			tok.Raw = r.buf[r.pos : r.pos+1]
+			r.Advance()
+			return tok, nil
+		}
+		if err != nil {
+			if l != nil {
+				err = r.sync(err)
+			}
+			return JSONToken{}, err
+		}
+
+		tok.Raw = l.data[:l.pos]
+		r.sync(nil)
+		return tok, nil
+	}
+}
+
+// ForEachArrayElement calls fn with the raw bytes of each element of the top-level array read from r
+// Only one element is buffered at a time, so memory is bounded by the largest element
+// raw is only valid during the call, fn may return ErrJSONStop to end early
+func ForEachArrayElement(r io.Reader, fn func(raw []byte) error) error {
+	reader := CreateJSONReader(r)
+	defer ReleaseJSONReader(reader)
+
+	if err := reader.Expect(_BracketLeft); err != nil {
+		return err
+	}
+
+	reader.SkipWhitespace()
+	if reader.Peek() == _BracketRight {
+		reader.Advance()
+		return reader.ExpectEOF()
+	}
+
+	for {
+		raw, err := reader.ReadRawValue()
+		if err != nil {
+			return err
+		}
+
+		if err := fn(raw); err != nil {
+			return jsonStopped(err)
+		}
+
+		reader.SkipWhitespace()
+		if reader.Peek() == _BracketRight {
+			reader.Advance()
+			return reader.ExpectEOF()
+		}
+		if err := reader.Expect(_CommaChar); err != nil {
+			return err
+		}
+	}
+}
+
+// ExpectEOF checks only whitespace is left in the input
+// It reads the rest of the input, so it is meant to be called after the last value
+func (r *JSONReader) ExpectEOF() error {
+	r.SkipWhitespace()
+	if r.pos < r.n {
+		return r.syntaxErrorMsg("unexpected data after value", nil)
+	}
+	return r.readErr()
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestJSONReaderNext(t *testing.T) {
+	inputs := []string{
+		`{"id": 9007199254740993, "name": "😀 gopher", "tags": ["a", "b"], "ok": true, "none": null}`,
+		`[1, -2.5e3, {"a": {"b": []}}, "x\"y"]`,
+		`"top" 12 false`,
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			l := CreateJSONLexer([]byte(input))
+			defer ReleaseJSONLexer(l)
+
+			r := CreateJSONReader(iotest.OneByteReader(strings.NewReader(input)))
+			defer ReleaseJSONReader(r)
+
+			for {
+				want, wantErr := l.Next()
+				got, err := r.Next()
+				if !errors.Is(err, wantErr) && (err == nil || wantErr == nil) {
+					t.Fatalf("expected error %v, got %v", wantErr, err)
+				}
+				if wantErr != nil {
+					break
+				}
+				if got.Kind != want.Kind || got.Value != want.Value || string(got.Raw) != string(want.Raw) {
+					t.Fatalf("expected %v %q, got %v %q", want.Kind, want.Raw, got.Kind, got.Raw)
+				}
+				if got.Offset != want.Offset || got.Line != want.Line || got.Column != want.Column {
+					t.Fatalf("expected position %d:%d:%d, got %d:%d:%d", want.Offset, want.Line, want.Column, got.Offset, got.Line, got.Column)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONReaderRead(t *testing.T) {
+	input := `{"id": 9007199254740993, "skip": {"deep": [1, {"x": "]"}]}, "tags": ["a", "b"], "raw": [true, null]}`
+
+	r := CreateJSONReader(iotest.OneByteReader(strings.NewReader(input)))
+	defer ReleaseJSONReader(r)
+
+	if err := r.Expect('{'); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; ; i++ {
+		key, err := r.ReadString()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Expect(':'); err != nil {
+			t.Fatal(err)
+		}
+
+		switch key {
+		case "id":
+			id, err := r.ReadUint64()
+			if err != nil || id != 9007199254740993 {
+				t.Fatalf("expected 9007199254740993, got %d %v", id, err)
+			}
+		case "skip":
+			if err := r.SkipValue(); err != nil {
+				t.Fatal(err)
+			}
+		case "tags":
+			tags, err := r.ReadArrayString()
+			if err != nil || len(tags) != 2 || tags[1] != "b" {
+				t.Fatalf("expected [a b], got %v %v", tags, err)
+			}
+		case "raw":
+			raw, err := r.ReadRawValue()
+			if err != nil || string(raw) != "[true, null]" {
+				t.Fatalf("expected raw [true, null], got %q %v", raw, err)
+			}
+		}
+
+		r.SkipWhitespace()
+		if r.Peek() == '}' {
+			r.Advance()
+			break
+		}
+		if err := r.Expect(','); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+// countingReader generates a large array without holding it in memory
+type countingReader struct {
+	elements int
+	emitted  int
+	pending  []byte
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		switch {
+		case c.emitted == 0:
+			c.pending = []byte(`[`)
+		case c.emitted <= c.elements:
+			c.pending = []byte(`{"id": 1, "pad": "` + strings.Repeat("x", 100) + `"}`)
+			if c.emitted < c.elements {
+				c.pending = append(c.pending, ',')
+			}
+		case c.emitted == c.elements+1:
+			c.pending = []byte(`]`)
+		default:
+			return 0, io.EOF
+		}
+		c.emitted++
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func TestForEachArrayElement(t *testing.T) {
+	src := &countingReader{elements: 100000}
+	count := 0
+
+	err := ForEachArrayElement(src, func(raw []byte) error {
+		count++
+		if len(raw) != 120 {
+			t.Fatalf("element %d: unexpected raw %q", count, raw)
+		}
+		return nil
+	})
+	if err != nil || count != 100000 {
+		t.Fatalf("expected 100000 elements, got %d %v", count, err)
+	}
+
+	count = 0
+	err = ForEachArrayElement(strings.NewReader(`[1, [2], {"a": 3}, 4]`), func(raw []byte) error {
+		count++
+		if count == 3 {
+			return ErrJSONStop
+		}
+		return nil
+	})
+	if err != nil || count != 3 {
+		t.Errorf("expected stop after 3 elements, got %d %v", count, err)
+	}
+
+	if err := ForEachArrayElement(strings.NewReader(`[] x`), func(raw []byte) error { return nil }); err == nil {
+		t.Error("expected error for trailing data")
+	}
+	if err := ForEachArrayElement(strings.NewReader(`[1, 2`), func(raw []byte) error { return nil }); err == nil {
+		t.Error("expected error for unclosed array")
+	}
+}
+
+func TestJSONReaderStrict(t *testing.T) {
+	tests := []struct {
+		input string
+		read  func(s JSONScanner) error
+	}{
+		{input: `true"x"`, read: func(s JSONScanner) error { _, err := s.ReadBool(); return err }},
+		{input: `false:`, read: func(s JSONScanner) error { _, err := s.ReadBool(); return err }},
+		{input: `null[`, read: func(s JSONScanner) error { return s.ReadNull() }},
+		{input: `12{`, read: func(s JSONScanner) error { _, err := s.ReadInt64(); return err }},
+	}
+
+	for _, tt := range tests {
+		l := CreateJSONLexer([]byte(tt.input))
+		r := CreateJSONReader(iotest.OneByteReader(strings.NewReader(tt.input)))
+
+		// both scanners check the byte after the literal, not only the buffered token
+		for _, s := range []JSONScanner{l, r} {
+			s.SetStrict(true)
+			var syntaxErr *JSONSyntaxError
+			if err := tt.read(s); !errors.As(err, &syntaxErr) {
+				t.Errorf("%T %s: expected *JSONSyntaxError, got %v", s, tt.input, err)
+			}
+		}
+
+		ReleaseJSONLexer(l)
+		ReleaseJSONReader(r)
+	}
+
+	r := CreateJSONReader(strings.NewReader(`[true,null]`))
+	defer ReleaseJSONReader(r)
+	r.SetStrict(true)
+
+	if err := r.Expect('['); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := r.ReadBool(); err != nil || !v {
+		t.Fatalf("expected true, got %v %v", v, err)
+	}
+	if err := r.Expect(','); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ReadNull(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Expect(']'); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,706 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONSchemaError is a value that does not match the schema
+type JSONSchemaError struct {
+	Path    string // JSON Pointer of the value in the document
+	Message string
+}
+
+func (e *JSONSchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// JSONSchemaErrors lists every mismatch found in a document
+type JSONSchemaErrors []*JSONSchemaError
+
+func (e JSONSchemaErrors) Error() string {
+
+	msgs := make([]string, len(e))
+
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// JSONSchema is a compiled JSON Schema, it is safe for concurrent use
+// The supported subset of draft 2020-12 is type, required, properties, additionalProperties,
+// enum, minimum, maximum, exclusiveMinimum, exclusiveMaximum, minLength, maxLength, pattern,
+// items, minItems, maxItems, format (email, hostname, uri, date-time) and $ref within the document
+// Other keywords are ignored, patterns use RE2 syntax
+type JSONSchema struct {
+	root *jsonSchemaNode
+}
+
+// jsonSchemaNode is a compiled schema
+type jsonSchemaNode struct {
+	never                bool // the false schema
+	ref                  *jsonSchemaNode
+	types                []string
+	enum                 []any
+	required             []string
+	properties           map[string]*jsonSchemaNode
+	additionalProperties *jsonSchemaNode
+	items                *jsonSchemaNode
+	minimum              *big.Rat
+	maximum              *big.Rat
+	exclusiveMinimum     *big.Rat
+	exclusiveMaximum     *big.Rat
+	minLength            int
+	maxLength            int
+	minItems             int
+	maxItems             int
+	pattern              *regexp.Regexp
+	format               string
+}
+
+// jsonSchemaCompiler compiles a schema document
+type jsonSchemaCompiler struct {
+	data []byte
+	refs map[string]*jsonSchemaNode
+}
+
+// CompileJSONSchema compiles the JSON Schema document schema
+func CompileJSONSchema(schema []byte) (*JSONSchema, error) {
+
+	v, err := decodeJSONValue(schema)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := &jsonSchemaCompiler{data: schema, refs: map[string]*jsonSchemaNode{}}
+
+	root, err := c.compile(v, "")
+
+	if err != nil {
+		return nil, err
+	}
+
+	for pointer, node := range c.refs {
+		if jsonSchemaRefCycle(node) {
+			return nil, fmt.Errorf("%w: $ref cycle at #%s", ErrJSONSchemaInvalid, pointer)
+		}
+	}
+
+	return &JSONSchema{root: root}, nil
+}
+
+// Validate checks data is a single JSON value matching the schema
+// Mismatches are returned as JSONSchemaErrors, malformed JSON as a syntax error
+func (s *JSONSchema) Validate(data []byte) error {
+
+	l := CreateJSONLexer(data)
+	defer ReleaseJSONLexer(l)
+
+	if err := s.ValidateLexer(l); err != nil {
+		return err
+	}
+
+	return l.ExpectEOF()
+}
+
+// ValidateLexer checks the next value of l against the schema in a single pass
+// Only values compared with enum or shared with $ref are held in memory
+func (s *JSONSchema) ValidateLexer(l *JSONLexer) error {
+
+	var errs JSONSchemaErrors
+
+	if err := validateJSONSchema(l, s.root, nil, &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// compile compiles the schema v found at pointer
+func (c *jsonSchemaCompiler) compile(v any, pointer string) (*jsonSchemaNode, error) {
+
+	switch s := v.(type) {
+	case bool:
+		return &jsonSchemaNode{never: !s, maxLength: -1, maxItems: -1}, nil
+	case map[string]any:
+		return c.compileObject(s, pointer)
+	default:
+		return nil, fmt.Errorf("%w: #%s is not a schema", ErrJSONSchemaInvalid, pointer)
+	}
+}
+
+// compileObject compiles the keywords of an object schema
+func (c *jsonSchemaCompiler) compileObject(s map[string]any, pointer string) (*jsonSchemaNode, error) {
+
+	n := &jsonSchemaNode{maxLength: -1, maxItems: -1}
+
+	invalid := func(keyword string) error {
+		return fmt.Errorf("%w: #%s", ErrJSONSchemaInvalid, pointer+JoinJSONPointer(keyword))
+	}
+
+	var err error
+
+	for keyword, value := range s {
+
+		switch keyword {
+		case "$ref":
+			ref, ok := value.(string)
+			if !ok {
+				return nil, invalid(keyword)
+			}
+			if n.ref, err = c.resolve(ref); err != nil {
+				return nil, err
+			}
+		case "type":
+			switch t := value.(type) {
+			case string:
+				n.types = []string{t}
+			case []any:
+				for _, item := range t {
+					name, ok := item.(string)
+					if !ok {
+						return nil, invalid(keyword)
+					}
+					n.types = append(n.types, name)
+				}
+			default:
+				return nil, invalid(keyword)
+			}
+			for _, name := range n.types {
+				switch name {
+				case "object", "array", "string", "number", "integer", "boolean", "null":
+				default:
+					return nil, invalid(keyword)
+				}
+			}
+		case "enum":
+			values, ok := value.([]any)
+			if !ok {
+				return nil, invalid(keyword)
+			}
+			n.enum = values
+		case "required":
+			names, ok := value.([]any)
+			if !ok {
+				return nil, invalid(keyword)
+			}
+			for _, item := range names {
+				name, ok := item.(string)
+				if !ok {
+					return nil, invalid(keyword)
+				}
+				n.required = append(n.required, name)
+			}
+		case "properties":
+			members, ok := value.(map[string]any)
+			if !ok {
+				return nil, invalid(keyword)
+			}
+			n.properties = make(map[string]*jsonSchemaNode, len(members))
+			for name, member := range members {
+				if n.properties[name], err = c.compile(member, pointer+JoinJSONPointer(keyword, name)); err != nil {
+					return nil, err
+				}
+			}
+		case "additionalProperties":
+			if n.additionalProperties, err = c.compile(value, pointer+JoinJSONPointer(keyword)); err != nil {
+				return nil, err
+			}
+		case "items":
+			if n.items, err = c.compile(value, pointer+JoinJSONPointer(keyword)); err != nil {
+				return nil, err
+			}
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			r, ok := jsonRat(value)
+			if !ok {
+				return nil, invalid(keyword)
+			}
+			switch keyword {
+			case "minimum":
+				n.minimum = r
+			case "maximum":
+				n.maximum = r
+			case "exclusiveMinimum":
+				n.exclusiveMinimum = r
+			default:
+				n.exclusiveMaximum = r
+			}
+		case "minLength", "maxLength", "minItems", "maxItems":
+			r, ok := jsonRat(value)
+			if !ok || !r.IsInt() || r.Sign() < 0 || !r.Num().IsInt64() || r.Num().Int64() > math.MaxInt32 {
+				return nil, invalid(keyword)
+			}
+			i := int(r.Num().Int64())
+			switch keyword {
+			case "minLength":
+				n.minLength = i
+			case "maxLength":
+				n.maxLength = i
+			case "minItems":
+				n.minItems = i
+			default:
+				n.maxItems = i
+			}
+		case "pattern":
+			expr, ok := value.(string)
+			if !ok {
+				return nil, invalid(keyword)
+			}
+			if n.pattern, err = regexp.Compile(expr); err != nil {
+				return nil, fmt.Errorf("%w: pattern at #%s: %v", ErrJSONSchemaInvalid, pointer, err)
+			}
+		case "format":
+			format, ok := value.(string)
+			if !ok {
+				return nil, invalid(keyword)
+			}
+			n.format = format
+		}
+	}
+
+	return n, nil
+}
+
+// resolve compiles the schema referenced by a fragment such as "#/$defs/name"
+// nodes are cached by pointer so recursive schemas compile once
+func (c *jsonSchemaCompiler) resolve(ref string) (*jsonSchemaNode, error) {
+
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("%w: $ref %q is not in the document", ErrJSONSchemaInvalid, ref)
+	}
+
+	pointer, err := url.PathUnescape(ref[1:])
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: $ref %q", ErrJSONSchemaInvalid, ref)
+	}
+
+	if n, ok := c.refs[pointer]; ok {
+		return n, nil
+	}
+
+	raw, err := JSONPointerGet(c.data, pointer)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: $ref %q: %v", ErrJSONSchemaInvalid, ref, err)
+	}
+
+	v, err := decodeJSONValue(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	n := &jsonSchemaNode{}
+	c.refs[pointer] = n
+
+	compiled, err := c.compile(v, pointer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	*n = *compiled
+
+	return n, nil
+}
+
+// jsonSchemaRefCycle reports whether following $ref from n leads back to a visited node
+func jsonSchemaRefCycle(n *jsonSchemaNode) bool {
+
+	seen := map[*jsonSchemaNode]bool{}
+
+	for ; n != nil; n = n.ref {
+
+		if seen[n] {
+			return true
+		}
+
+		seen[n] = true
+	}
+
+	return false
+}
+
+// validateJSONSchema checks the next value of l against n and appends mismatches to errs
+func validateJSONSchema(l *JSONLexer, n *jsonSchemaNode, path []string, errs *JSONSchemaErrors) error {
+
+	if n.ref == nil && n.enum == nil {
+		return validateJSONSchemaValue(l, n, path, errs)
+	}
+
+	// the value is checked more than once, keep it
+	l.SkipWhitespace()
+
+	raw, err := l.ReadRawValue()
+
+	if err != nil {
+		return err
+	}
+
+	if n.enum != nil {
+
+		v, err := decodeJSONValue(raw)
+
+		if err != nil {
+			return err
+		}
+
+		found := false
+
+		for _, e := range n.enum {
+			if jsonEqual(v, e) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			errs.add(path, "value is not one of the enum values")
+		}
+	}
+
+	if n.ref != nil {
+		if err := validateJSONSchemaRef(raw, n.ref, path, errs); err != nil {
+			return err
+		}
+	}
+
+	return validateJSONSchemaRaw(raw, n, path, errs)
+}
+
+// validateJSONSchemaRef checks raw against the whole referenced node, including its own $ref and enum
+func validateJSONSchemaRef(raw []byte, n *jsonSchemaNode, path []string, errs *JSONSchemaErrors) error {
+
+	l := CreateJSONLexer(raw)
+	defer ReleaseJSONLexer(l)
+
+	return validateJSONSchema(l, n, path, errs)
+}
+
+// validateJSONSchemaRaw checks raw against n, leaving out $ref and enum
+func validateJSONSchemaRaw(raw []byte, n *jsonSchemaNode, path []string, errs *JSONSchemaErrors) error {
+
+	l := CreateJSONLexer(raw)
+	defer ReleaseJSONLexer(l)
+
+	if n.ref != nil || n.enum != nil {
+		own := *n
+		own.ref, own.enum = nil, nil
+		n = &own
+	}
+
+	return validateJSONSchemaValue(l, n, path, errs)
+}
+
+// validateJSONSchemaValue checks the next value of l against the keywords of n other than $ref and enum
+func validateJSONSchemaValue(l *JSONLexer, n *jsonSchemaNode, path []string, errs *JSONSchemaErrors) error {
+
+	l.SkipWhitespace()
+
+	if n.never {
+		errs.add(path, "value is not allowed")
+		return l.SkipValue()
+	}
+
+	switch jsonKindOf(l.Peek()) {
+	case JSONTokenObjectStart:
+		if !n.allows("object") {
+			errs.add(path, n.typeMessage())
+			return l.SkipValue()
+		}
+		return validateJSONSchemaObject(l, n, path, errs)
+	case JSONTokenArrayStart:
+		if !n.allows("array") {
+			errs.add(path, n.typeMessage())
+			return l.SkipValue()
+		}
+		return validateJSONSchemaArray(l, n, path, errs)
+	case JSONTokenString:
+		s, err := l.ReadString()
+		if err != nil {
+			return err
+		}
+		if !n.allows("string") {
+			errs.add(path, n.typeMessage())
+			return nil
+		}
+		n.checkString(s, path, errs)
+	case JSONTokenNumber:
+		raw, err := l.ReadNumberRaw()
+		if err != nil {
+			return err
+		}
+		r, ok := new(big.Rat).SetString(string(raw))
+		if !ok {
+			return l.syntaxErrorMsg("invalid number", nil)
+		}
+		if !n.allows("number") && !(r.IsInt() && n.allows("integer")) {
+			errs.add(path, n.typeMessage())
+			return nil
+		}
+		n.checkNumber(r, path, errs)
+	case JSONTokenBool:
+		if _, err := l.ReadBool(); err != nil {
+			return err
+		}
+		if !n.allows("boolean") {
+			errs.add(path, n.typeMessage())
+		}
+	case JSONTokenNull:
+		if err := l.ReadNull(); err != nil {
+			return err
+		}
+		if !n.allows("null") {
+			errs.add(path, n.typeMessage())
+		}
+	default:
+		// report the syntax error
+		return l.SkipValue()
+	}
+
+	return nil
+}
+
+// validateJSONSchemaObject checks the members of the object at l
+func validateJSONSchemaObject(l *JSONLexer, n *jsonSchemaNode, path []string, errs *JSONSchemaErrors) error {
+
+	if err := l.checkDepth(len(path) + 1); err != nil {
+		return err
+	}
+
+	var found []bool
+
+	if len(n.required) > 0 {
+		found = make([]bool, len(n.required))
+	}
+
+	l.Advance()
+	l.SkipWhitespace()
+
+	if l.Peek() == _BraceRight {
+		l.Advance()
+	} else {
+		for {
+			key, err := l.ReadString()
+
+			if err != nil {
+				return err
+			}
+
+			if err := l.Expect(_ColonChar); err != nil {
+				return err
+			}
+
+			for i, name := range n.required {
+				if name == key {
+					found[i] = true
+				}
+			}
+
+			child, ok := n.properties[key]
+
+			if !ok {
+				child = n.additionalProperties
+			}
+
+			if child == nil {
+				err = l.SkipValue()
+			} else if child.never && !ok {
+				errs.add(append(path, key), "additional property is not allowed")
+				err = l.SkipValue()
+			} else {
+				err = validateJSONSchema(l, child, append(path, key), errs)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			l.SkipWhitespace()
+
+			if l.Peek() == _BraceRight {
+				l.Advance()
+				break
+			}
+
+			if err := l.Expect(_CommaChar); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, name := range n.required {
+		if !found[i] {
+			errs.add(path, fmt.Sprintf("missing required property %q", name))
+		}
+	}
+
+	return nil
+}
+
+// validateJSONSchemaArray checks the elements of the array at l
+func validateJSONSchemaArray(l *JSONLexer, n *jsonSchemaNode, path []string, errs *JSONSchemaErrors) error {
+
+	if err := l.checkDepth(len(path) + 1); err != nil {
+		return err
+	}
+
+	count := 0
+
+	l.Advance()
+	l.SkipWhitespace()
+
+	if l.Peek() == _BracketRight {
+		l.Advance()
+	} else {
+		for {
+			var err error
+
+			if n.items == nil {
+				err = l.SkipValue()
+			} else {
+				err = validateJSONSchema(l, n.items, append(path, strconv.Itoa(count)), errs)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			count++
+
+			l.SkipWhitespace()
+
+			if l.Peek() == _BracketRight {
+				l.Advance()
+				break
+			}
+
+			if err := l.Expect(_CommaChar); err != nil {
+				return err
+			}
+		}
+	}
+
+	if count < n.minItems {
+		errs.add(path, fmt.Sprintf("array has %d items, minimum is %d", count, n.minItems))
+	}
+
+	if n.maxItems >= 0 && count > n.maxItems {
+		errs.add(path, fmt.Sprintf("array has %d items, maximum is %d", count, n.maxItems))
+	}
+
+	return nil
+}
+
+// allows reports whether the type keyword allows typ
+func (n *jsonSchemaNode) allows(typ string) bool {
+
+	if n.types == nil {
+		return true
+	}
+
+	for _, t := range n.types {
+		if t == typ {
+			return true
+		}
+	}
+
+	return false
+}
+
+// typeMessage describes the expected type
+func (n *jsonSchemaNode) typeMessage() string {
+	return "value must be of type " + strings.Join(n.types, " or ")
+}
+
+// checkString checks the string keywords
+func (n *jsonSchemaNode) checkString(s string, path []string, errs *JSONSchemaErrors) {
+
+	if n.minLength > 0 || n.maxLength >= 0 {
+
+		length := utf8.RuneCountInString(s)
+
+		if length < n.minLength {
+			errs.add(path, fmt.Sprintf("string is shorter than %d characters", n.minLength))
+		}
+
+		if n.maxLength >= 0 && length > n.maxLength {
+			errs.add(path, fmt.Sprintf("string is longer than %d characters", n.maxLength))
+		}
+	}
+
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		errs.add(path, fmt.Sprintf("string does not match pattern /%s/", n.pattern))
+	}
+
+	if n.format != "" && !matchJSONSchemaFormat(n.format, s) {
+		errs.add(path, fmt.Sprintf("string is not a valid %s", n.format))
+	}
+}
+
+// checkNumber checks the number keywords
+func (n *jsonSchemaNode) checkNumber(r *big.Rat, path []string, errs *JSONSchemaErrors) {
+
+	if n.minimum != nil && r.Cmp(n.minimum) < 0 {
+		errs.add(path, "number must be >= "+n.minimum.RatString())
+	}
+
+	if n.maximum != nil && r.Cmp(n.maximum) > 0 {
+		errs.add(path, "number must be <= "+n.maximum.RatString())
+	}
+
+	if n.exclusiveMinimum != nil && r.Cmp(n.exclusiveMinimum) <= 0 {
+		errs.add(path, "number must be > "+n.exclusiveMinimum.RatString())
+	}
+
+	if n.exclusiveMaximum != nil && r.Cmp(n.exclusiveMaximum) >= 0 {
+		errs.add(path, "number must be < "+n.exclusiveMaximum.RatString())
+	}
+}
+
+// matchJSONSchemaFormat checks s against a format, unknown formats always match
+func matchJSONSchemaFormat(format, s string) bool {
+
+	switch format {
+	case "email":
+		return MatchEmail(s)
+	case "hostname":
+		return MatchDomain(s)
+	case "uri":
+		u, err := url.Parse(s)
+		if err != nil || u.Scheme == "" {
+			return false
+		}
+		host := u.Hostname()
+		return host == "" || net.ParseIP(host) != nil || MatchDomain(host) || !strings.Contains(host, ".")
+	case "date-time":
+		_, err := time.Parse(time.RFC3339Nano, s)
+		return err == nil
+	default:
+		return true
+	}
+}
+
+// add appends a mismatch at path
+func (e *JSONSchemaErrors) add(path []string, message string) {
+	*e = append(*e, &JSONSchemaError{Path: JoinJSONPointer(path...), Message: message})
+}
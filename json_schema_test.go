@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const testJSONSchema = `{
+	"type": "object",
+	"required": ["name", "email"],
+	"properties": {
+		"name": {"type": "string", "minLength": 2, "maxLength": 8, "pattern": "^[a-z]+$"},
+		"email": {"type": "string", "format": "email"},
+		"site": {"type": "string", "format": "uri"},
+		"created": {"type": "string", "format": "date-time"},
+		"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+		"role": {"enum": ["admin", "user", 1]},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"parent": {"$ref": "#/$defs/node"},
+		"color": {"$ref": "#/$defs/color"},
+		"label": {"$ref": "#/$defs/label"}
+	},
+	"additionalProperties": false,
+	"$defs": {
+		"node": {
+			"type": ["object", "null"],
+			"properties": {"child": {"$ref": "#/$defs/node"}, "id": {"type": "number"}}
+		},
+		"color": {"enum": ["red", "green"]},
+		"label": {"$ref": "#/$defs/text"},
+		"text": {"type": "string"}
+	}
+}`
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, err := CompileJSONSchema([]byte(testJSONSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "valid",
+			doc: `{"name": "bob", "email": "bob@example.com", "site": "https://example.com/x", "created": "2024-01-02T03:04:05Z",
+				"age": 42.0, "role": 1.0, "tags": ["a"], "parent": {"id": 1, "child": {"child": null}},
+				"color": "red", "label": "x"}`,
+		},
+		{
+			name: "required",
+			doc:  `{}`,
+			want: []string{`missing required property "name"`, `missing required property "email"`},
+		},
+		{
+			name: "strings",
+			doc:  `{"name": "BOBBY BOBSON", "email": "bob", "site": "example.com", "created": "yesterday"}`,
+			want: []string{
+				"/name: string is longer than 8 characters",
+				"/name: string does not match pattern /^[a-z]+$/",
+				"/email: string is not a valid email",
+				"/site: string is not a valid uri",
+				"/created: string is not a valid date-time",
+			},
+		},
+		{
+			name: "numbers",
+			doc:  `{"name": "bob", "email": "b@x.io", "age": 1.5}`,
+			want: []string{"/age: value must be of type integer"},
+		},
+		{
+			name: "bounds",
+			doc:  `{"name": "bob", "email": "b@x.io", "age": 150}`,
+			want: []string{"/age: number must be < 150"},
+		},
+		{
+			name: "enum and items",
+			doc:  `{"name": "bob", "email": "b@x.io", "role": "root", "tags": ["a", 2, "c"]}`,
+			want: []string{
+				"/role: value is not one of the enum values",
+				"/tags/1: value must be of type string",
+				"/tags: array has 3 items, maximum is 2",
+			},
+		},
+		{
+			name: "additional",
+			doc:  `{"name": "bob", "email": "b@x.io", "x/y": {"deep": [1]}}`,
+			want: []string{"/x~1y: additional property is not allowed"},
+		},
+		{
+			name: "recursive ref",
+			doc:  `{"name": "bob", "email": "b@x.io", "parent": {"child": {"child": {"id": "x"}}}}`,
+			want: []string{"/parent/child/child/id: value must be of type number"},
+		},
+		{
+			name: "enum in ref",
+			doc:  `{"name": "bob", "email": "b@x.io", "color": "blue"}`,
+			want: []string{"/color: value is not one of the enum values"},
+		},
+		{
+			name: "chained ref",
+			doc:  `{"name": "bob", "email": "b@x.io", "label": 1}`,
+			want: []string{"/label: value must be of type string"},
+		},
+		{
+			name: "root type",
+			doc:  `[1]`,
+			want: []string{"value must be of type object"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tt.doc))
+
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var errs JSONSchemaErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected JSONSchemaErrors, got %v", err)
+			}
+
+			got := make([]string, len(errs))
+			for i, e := range errs {
+				got[i] = e.Error()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	var errs JSONSchemaErrors
+	if err := schema.Validate([]byte(`{"name": "bob", "email": }`)); err == nil || errors.As(err, &errs) {
+		t.Errorf("expected syntax error, got %v", err)
+	}
+}
+
+func TestCompileJSONSchemaInvalid(t *testing.T) {
+	tests := []string{
+		`{"type": "text"}`,
+		`{"minLength": -1}`,
+		`{"pattern": "("}`,
+		`{"$ref": "other.json#/a"}`,
+		`{"$ref": "#/$defs/missing"}`,
+		`{"$ref": "#"}`,
+		`{"properties": {"a": 1}}`,
+	}
+
+	for _, schema := range tests {
+		if _, err := CompileJSONSchema([]byte(schema)); !errors.Is(err, ErrJSONSchemaInvalid) {
+			t.Errorf("%s: expected ErrJSONSchemaInvalid, got %v", schema, err)
+		}
+	}
+}
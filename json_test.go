@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: `{a: 1, 'b': [true, null,], /* c */}`, want: `{"a":1,"b":[true,null]}`},
+		{input: "// only a comment\n[\"x\" // end\n]", want: `["x"]`},
+		{input: `{'q': 'say "hi"'}`, want: `{"q":"say \"hi\""}`},
+		{input: `[1,,2]`, wantErr: true},
+		{input: `[,]`, wantErr: true},
+		{input: `{a: 1 /* open`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := StripJSONC([]byte(tt.input))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %s", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestReadJSONC(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.jsonc")
+
+	config := "{\n  // listen address\n  addr: ':8080',\n  hosts: ['a', 'b',],\n}\n"
+	if err := os.WriteFile(filename, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var v struct {
+		Addr  string   `json:"addr"`
+		Hosts []string `json:"hosts"`
+	}
+	if err := ReadJSONC(filename, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Addr != ":8080" || len(v.Hosts) != 2 {
+		t.Errorf("unexpected config %+v", v)
+	}
+
+	if err := os.WriteFile(filename, []byte("{\n  addr: ':8080'\n  port: 1\n}"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var syntaxErr *JSONSyntaxError
+	if err := ReadJSONC(filename, &v); !errors.As(err, &syntaxErr) || syntaxErr.Line != 3 {
+		t.Errorf("expected syntax error on line 3, got %v", err)
+	}
+}
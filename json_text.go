@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"bufio"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 )
 
@@ -10,7 +12,14 @@ import (
 // using buf as a scratch buffer. It starts at position pos in buf, where n bytes are available.
 // Whitespace is defined as space (' '), newline ('\n'), carriage return ('\r'), or tab ('\t') per JSON (RFC 8259).
 // Returns the position of the first non-whitespace character, the number of bytes available in buf, and any error.
+// Error offsets count from buf[0] as passed in, the input offset when reading starts with an empty buffer.
 func ReadUntilNonWhitespace(r io.Reader, buf []byte, pos int, n int) (int, int, error) {
+	base := 0
+	return readUntilNonWhitespace(r, buf, pos, n, &base)
+}
+
+// readUntilNonWhitespace is ReadUntilNonWhitespace adding the bytes dropped from buf to base.
+func readUntilNonWhitespace(r io.Reader, buf []byte, pos int, n int, base *int) (int, int, error) {
 
 	l := len(buf)
 
@@ -46,6 +55,7 @@ func ReadUntilNonWhitespace(r io.Reader, buf []byte, pos int, n int) (int, int,
 		if n == 0 {
 			return pos, n, io.EOF // No non-whitespace character found
 		}
+		*base += pos
 		pos = 0
 	}
 }
@@ -54,149 +64,167 @@ func ReadUntilNonWhitespace(r io.Reader, buf []byte, pos int, n int) (int, int,
 // using buffer `buf` with current offset `pos` and length `n`.
 // It handles JSON escape sequences and Unicode surrogate pairs properly.
 // Returns the parsed string, new position in buffer, or an error.
+// Error offsets count from buf[0] as passed in, the input offset when reading starts with an empty buffer.
 func ReadString(r io.Reader, buf []byte, pos, n int) (string, int, error) {
+	base := 0
+	return readJSONTextString(r, buf, pos, n, &base)
+}
+
+// readJSONTextString is ReadString adding the bytes dropped from buf to base.
+func readJSONTextString(r io.Reader, buf []byte, pos, n int, base *int) (string, int, error) {
+
+	var result strings.Builder
+	result.Grow(64) // Preallocate capacity to avoid frequent realloc
+
+	pos, _, err := scanJSONTextString(r, buf, pos, n, base, &result, 0)
+
+	if err != nil {
+		return "", pos, err
+	}
+
+	return result.String(), pos, nil
+}
+
+// scanJSONTextString reads a JSON string writing its value to out, or only checking it when out is nil.
+// A string longer than max bytes in the input fails as soon as the limit is passed, 0 means no limit.
+// Returns the new position and the number of bytes available in buf.
+func scanJSONTextString(r io.Reader, buf []byte, pos, n int, base *int, out *strings.Builder, max int) (int, int, error) {
 	// Refill buffer if position has already reached the end
 	if pos >= n {
 		var err error
 		n, err = r.Read(buf)
 		if err != nil {
-			return "", pos, err
+			return pos, n, err
 		}
+		*base += pos
 		pos = 0
 	}
 
 	// JSON strings must start with a quote character
 	if buf[pos] != '"' {
-		return "", pos, fmt.Errorf("expecting '\"' at position %d", pos)
+		return pos, n, jsonTextError(*base+pos, "'\"'", jsonQuoteByte(buf[pos]), nil)
 	}
+	start := *base + pos
 	pos++
 
-	var result strings.Builder
-	result.Grow(64) // Preallocate capacity to avoid frequent realloc
-
-	const (
-		StateNormal  = iota // Normal character parsing
-		StateEscape         // Parsing after encountering a backslash '\'
-		StateUnicode        // Parsing 4-digit hex unicode escape \uXXXX
-	)
-
-	state := StateNormal
+	length := 0            // Bytes read between the quotes
 	var unicodeBuf [4]byte // Holds 4 hex digits for \uXXXX
 	var surrogateHi rune   // Holds high surrogate, if any
 
-	for {
-		// If we reach the end of buffer, refill it
+	// next returns the next byte of the string, refilling the buffer and checking max
+	next := func() (byte, error) {
 		if pos >= n {
 			n = copy(buf, buf[pos:n]) // shift remaining data to front
+			*base += pos
 			pos = 0
 			n2, err := r.Read(buf[n:])
 			n += n2
 			if err != nil && err != io.EOF {
-				return "", pos, err
+				return 0, err
 			}
 			if n == 0 {
-				return "", pos, fmt.Errorf("unexpected EOF while parsing string")
+				return 0, &JSONSyntaxError{Offset: *base + pos, Msg: "unterminated string"}
 			}
 		}
-
 		c := buf[pos]
 		pos++
+		length++
+		// the closing quote may follow the last byte allowed
+		if max > 0 && (length > max+1 || length > max && c != '"') {
+			return 0, &JSONSyntaxError{Offset: start, Err: ErrJSONStringTooLong}
+		}
+		return c, nil
+	}
 
-		switch state {
-		case StateNormal:
-			switch c {
-			case '"':
-				// End of string
-				if surrogateHi != 0 {
-					return "", pos, fmt.Errorf("incomplete unicode surrogate pair")
-				}
-				return result.String(), pos, nil
-			case '\\':
-				// Escape starts
-				state = StateEscape
-			default:
-				// Normal character
-				result.WriteByte(c)
-			}
+	for {
+		c, err := next()
+		if err != nil {
+			return pos, n, err
+		}
 
-		case StateEscape:
-			switch c {
-			case '"', '\\', '/':
-				result.WriteByte(c)
-				state = StateNormal
-			case 'b':
-				result.WriteByte('\b')
-				state = StateNormal
-			case 'f':
-				result.WriteByte('\f')
-				state = StateNormal
-			case 'n':
-				result.WriteByte('\n')
-				state = StateNormal
-			case 'r':
-				result.WriteByte('\r')
-				state = StateNormal
-			case 't':
-				result.WriteByte('\t')
-				state = StateNormal
-			case 'u':
-				// Begin parsing \uXXXX
-				state = StateUnicode
-			default:
-				return "", pos, fmt.Errorf("invalid escape sequence \\%c", c)
+		if surrogateHi != 0 && c != '\\' {
+			return pos, n, &JSONSyntaxError{Offset: *base + pos - 1, Msg: "incomplete unicode surrogate pair"}
+		}
+
+		switch c {
+		case '"':
+			// End of string
+			return pos, n, nil
+		case '\\':
+			// Escape sequence
+			if c, err = next(); err != nil {
+				return pos, n, err
 			}
+		default:
+			// Normal character
+			if out != nil {
+				out.WriteByte(c)
+			}
+			continue
+		}
+
+		if surrogateHi != 0 && c != 'u' {
+			return pos, n, &JSONSyntaxError{Offset: *base + pos - 1, Msg: "incomplete unicode surrogate pair"}
+		}
 
-		case StateUnicode:
-			// Read next 4 hex digits from the stream into unicodeBuf
-			for i := 0; i < 4; i++ {
-				if pos >= n {
-					copy(buf, buf[pos:n])
-					n = n - pos
-					pos = 0
-					n2, err := r.Read(buf[n:])
-					n += n2
-					if err != nil && err != io.EOF {
-						return "", pos, err
-					}
-					if n < 4 {
-						return "", pos, fmt.Errorf("incomplete \\uXXXX escape")
-					}
+		var e byte
+		switch c {
+		case '"', '\\', '/':
+			e = c
+		case 'b':
+			e = '\b'
+		case 'f':
+			e = '\f'
+		case 'n':
+			e = '\n'
+		case 'r':
+			e = '\r'
+		case 't':
+			e = '\t'
+		case 'u':
+			// Read the 4 hex digits of \uXXXX
+			for i := range unicodeBuf {
+				if unicodeBuf[i], err = next(); err != nil {
+					return pos, n, err
 				}
-				unicodeBuf[i] = buf[pos]
-				pos++
 			}
+		default:
+			return pos, n, jsonTextError(*base+pos-1, "escape character", jsonQuoteByte(c), nil)
+		}
 
-			// Parse 4 hex digits into a Unicode code point
-			code, err := parseHex4(unicodeBuf[:])
-			if err != nil {
-				return "", pos, fmt.Errorf("invalid \\u escape: %w", err)
+		if c != 'u' {
+			if out != nil {
+				out.WriteByte(e)
 			}
-			r1 := rune(code)
-
-			// Handle surrogate pairs: \uD800–\uDBFF followed by \uDC00–\uDFFF
-			if isSurrogate(r1) {
-				if surrogateHi == 0 && isHighSurrogate(r1) {
-					// Save the high surrogate and expect a low surrogate next
-					surrogateHi = r1
-					state = StateEscape // Expecting next \uXXXX
-					continue
-				} else if surrogateHi != 0 && isLowSurrogate(r1) {
-					// Combine high and low surrogates into a full codepoint
-					full := decodeRune(surrogateHi, r1)
-					result.WriteRune(full)
-					surrogateHi = 0
-				} else {
-					return "", pos, fmt.Errorf("invalid unicode surrogate sequence")
-				}
+			continue
+		}
+
+		// Parse 4 hex digits into a Unicode code point
+		code, err := parseHex4(unicodeBuf[:])
+		if err != nil {
+			return pos, n, &JSONSyntaxError{Offset: *base + pos, Msg: "invalid unicode escape", Err: err}
+		}
+		r1 := rune(code)
+
+		// Handle surrogate pairs: \uD800–\uDBFF followed by \uDC00–\uDFFF
+		if isSurrogate(r1) {
+			if surrogateHi == 0 && isHighSurrogate(r1) {
+				// Save the high surrogate and expect a low surrogate next
+				surrogateHi = r1
+				continue
+			} else if surrogateHi != 0 && isLowSurrogate(r1) {
+				// Combine high and low surrogates into a full codepoint
+				r1 = decodeRune(surrogateHi, r1)
+				surrogateHi = 0
 			} else {
-				if surrogateHi != 0 {
-					return "", pos, fmt.Errorf("unexpected low surrogate without high surrogate")
-				}
-				result.WriteRune(r1)
+				return pos, n, &JSONSyntaxError{Offset: *base + pos, Msg: "invalid unicode surrogate sequence"}
 			}
+		} else if surrogateHi != 0 {
+			return pos, n, &JSONSyntaxError{Offset: *base + pos, Msg: "unexpected low surrogate without high surrogate"}
+		}
 
-			// Resume normal parsing after unicode
-			state = StateNormal
+		if out != nil {
+			out.WriteRune(r1)
 		}
 	}
 }
@@ -204,163 +232,136 @@ func ReadString(r io.Reader, buf []byte, pos, n int) (string, int, error) {
 // ReadNumber reads a JSON number from io.Reader, using buf as a scratch buffer.
 // It starts reading at position pos in buf, where n bytes are available.
 // Returns the parsed number as a string, the new position, and any error.
+// Error offsets count from buf[0] as passed in, the input offset when reading starts with an empty buffer.
 func ReadNumber(r io.Reader, buf []byte, pos, n int) (string, int, error) {
+	base := 0
+	s, pos, _, err := readJSONTextNumber(r, buf, pos, n, &base)
+	return s, pos, err
+}
+
+// readJSONTextNumber is ReadNumber adding the bytes dropped from buf to base.
+// It also returns the number of bytes available in buf, which changes when a number crosses a refill.
+func readJSONTextNumber(r io.Reader, buf []byte, pos, n int, base *int) (string, int, int, error) {
 	// Validate buffer state
 	if n > len(buf) {
-		return "", pos, fmt.Errorf("invalid buffer: n (%d) exceeds buffer length (%d)", n, len(buf))
+		return "", pos, n, fmt.Errorf("invalid buffer: n (%d) exceeds buffer length (%d)", n, len(buf))
 	}
 
 	// Use strings.Builder for efficient number construction
 	var result strings.Builder
-	result.Grow(n - pos) // Pre-allocate capacity
-	startPos := pos      // Track start position for error reporting
+	var readErr error
 
-	// Ensure buffer has data
-	if pos >= n {
-		n, err := r.Read(buf)
-		if err != nil && err != io.EOF {
-			return "", pos, fmt.Errorf("read error at position %d: %w", pos, err)
+	// peek returns the next byte, reading more input at the end of buf, or 0 at the end of the input
+	peek := func() byte {
+		for pos >= n && readErr == nil {
+			*base += pos
+			pos = 0
+			n, readErr = r.Read(buf)
 		}
-		if n == 0 {
-			return "", pos, fmt.Errorf("expected digit at position %d: %w", pos, io.EOF)
+		if pos >= n {
+			return 0
 		}
-		pos = 0
+		return buf[pos]
 	}
 
-	// Handle minus sign
-	c := buf[pos]
-	if c == '-' {
-		result.WriteByte(c)
-		pos++
+	// got describes the next byte for errors
+	got := func(c byte) string {
 		if pos >= n {
-			n, err := r.Read(buf)
-			if err != nil && err != io.EOF {
-				return "", pos, fmt.Errorf("read error at position %d: %w", pos, err)
-			}
-			if n == 0 {
-				return "", pos, fmt.Errorf("incomplete number: only minus sign at position %d", startPos)
-			}
-			pos = 0
+			return "EOF"
 		}
-		c = buf[pos]
+		return jsonQuoteByte(c)
 	}
 
-	// Handle integer part
-	if c == '0' {
+	// digits copies a run of digits and returns its length
+	digits := func() int {
+		count := 0
+		for c := peek(); c >= '0' && c <= '9'; c = peek() {
+			result.WriteByte(c)
+			pos++
+			count++
+		}
+		return count
+	}
+
+	// Handle minus sign
+	c := peek()
+	if pos >= n {
+		return "", pos, n, jsonTextError(*base+pos, "digit", "EOF", io.EOF)
+	}
+	if c == '-' {
 		result.WriteByte(c)
 		pos++
-		// JSON forbids leading zeros (e.g., "01" or "-01")
-		if pos < n && buf[pos] >= '0' && buf[pos] <= '9' {
-			return "", pos, fmt.Errorf("invalid number: leading zero followed by digit at position %d", pos)
-		}
-	} else if c >= '1' && c <= '9' {
+		c = peek()
+	}
+
+	// Handle integer part, JSON forbids leading zeros (e.g., "01" or "-01")
+	switch {
+	case c == '0' && pos < n:
 		result.WriteByte(c)
 		pos++
-		// Read subsequent digits
-		for pos < n {
-			c = buf[pos]
-			if c >= '0' && c <= '9' {
-				result.WriteByte(c)
-				pos++
-			} else {
-				break
-			}
+		if c := peek(); c >= '0' && c <= '9' {
+			return "", pos, n, &JSONSyntaxError{Offset: *base + pos, Msg: "invalid number: leading zero followed by digit"}
 		}
-	} else {
-		return "", pos, fmt.Errorf("expected digit at position %d, got %q", pos, c)
+	case c >= '1' && c <= '9':
+		digits()
+	default:
+		return "", pos, n, jsonTextError(*base+pos, "digit", got(c), nil)
 	}
 
-	// Handle fraction part (optional)
-	if pos < n && buf[pos] == '.' {
+	// Handle fraction part (optional), at least one digit must follow the decimal point
+	if peek() == '.' && pos < n {
 		result.WriteByte('.')
 		pos++
-		// Require at least one digit after decimal point
-		if pos >= n {
-			n, err := r.Read(buf)
-			if err != nil && err != io.EOF {
-				return "", pos, fmt.Errorf("read error at position %d: %w", pos, err)
-			}
-			if n == 0 {
-				return "", pos, fmt.Errorf("incomplete number: no digits after decimal point at position %d", startPos)
-			}
-			pos = 0
-		}
-		c = buf[pos]
-		if c < '0' || c > '9' {
-			return "", pos, fmt.Errorf("expected digit after decimal point at position %d, got %q", pos, c)
-		}
-		result.WriteByte(c)
-		pos++
-		// Read additional digits
-		for pos < n {
-			c = buf[pos]
-			if c >= '0' && c <= '9' {
-				result.WriteByte(c)
-				pos++
-			} else {
-				break
-			}
+		if digits() == 0 {
+			return "", pos, n, jsonTextError(*base+pos, "digit after decimal point", got(peek()), nil)
 		}
 	}
 
-	// Handle exponent part (optional)
-	if pos < n && (buf[pos] == 'e' || buf[pos] == 'E') {
-		result.WriteByte(buf[pos])
+	// Handle exponent part (optional) with an optional sign, at least one digit must follow
+	if c := peek(); (c == 'e' || c == 'E') && pos < n {
+		result.WriteByte(c)
 		pos++
-		// Handle optional sign
-		if pos < n && (buf[pos] == '+' || buf[pos] == '-') {
-			result.WriteByte(buf[pos])
+		if c := peek(); (c == '+' || c == '-') && pos < n {
+			result.WriteByte(c)
 			pos++
 		}
-		// Require at least one digit after exponent
-		if pos >= n {
-			n, err := r.Read(buf)
-			if err != nil && err != io.EOF {
-				return "", pos, fmt.Errorf("read error at position %d: %w", pos, err)
-			}
-			if n == 0 {
-				return "", pos, fmt.Errorf("incomplete number: no digits after exponent at position %d", startPos)
-			}
-			pos = 0
-		}
-		c = buf[pos]
-		if c < '0' || c > '9' {
-			return "", pos, fmt.Errorf("expected digit after exponent at position %d, got %q", pos, c)
-		}
-		result.WriteByte(c)
-		pos++
-		// Read additional digits
-		for pos < n {
-			c = buf[pos]
-			if c >= '0' && c <= '9' {
-				result.WriteByte(c)
-				pos++
-			} else {
-				break
-			}
+		if digits() == 0 {
+			return "", pos, n, jsonTextError(*base+pos, "digit in exponent", got(peek()), nil)
 		}
 	}
 
-	// Ensure a valid number was parsed
-	if result.Len() == 0 || (result.Len() == 1 && result.String() == "-") {
-		return "", pos, fmt.Errorf("invalid number at position %d", startPos)
+	if readErr != nil && readErr != io.EOF {
+		return "", pos, n, fmt.Errorf("read error at position %d: %w", *base+pos, readErr)
 	}
 
-	return result.String(), pos, nil
+	return result.String(), pos, n, nil
 }
 
 // SkipValue skips a single JSON value from io.Reader, using buf as a scratch buffer.
 // It starts at position pos in buf, where n bytes are available.
 // The value can be a string, number, object, array, true, false, or null (per RFC 8259).
 // Returns the new position, number of bytes available in buf, and any error.
+// Error offsets count from buf[0] as passed in, the input offset when reading starts with an empty buffer.
 func SkipValue(r io.Reader, buf []byte, pos, n int) (newPos, newN int, err error) {
+	return SkipValueLimits(r, buf, pos, n, JSONLimits{})
+}
+
+// SkipValueLimits is SkipValue with limits on nesting, string length and token count.
+// Violations return a *JSONSyntaxError wrapping ErrJSONTooDeep, ErrJSONStringTooLong or ErrJSONTooManyTokens.
+func SkipValueLimits(r io.Reader, buf []byte, pos, n int, limits JSONLimits) (newPos, newN int, err error) {
+	base, tokens := 0, 0
+	return skipValue(r, buf, pos, n, &base, &limits, &tokens)
+}
+
+// skipValue skips a value counting its tokens in tokens and the bytes dropped from buf in base.
+func skipValue(r io.Reader, buf []byte, pos, n int, base *int, limits *JSONLimits, tokens *int) (newPos, newN int, err error) {
 	// Validate buffer state
 	if n > len(buf) {
 		return pos, n, fmt.Errorf("invalid buffer: n (%d) exceeds buffer length (%d)", n, len(buf))
 	}
 
 	// Skip leading whitespace
-	pos, n, err = ReadUntilNonWhitespace(r, buf, pos, n)
+	pos, n, err = readUntilNonWhitespace(r, buf, pos, n, base)
 	if err != nil {
 		return pos, n, err
 	}
@@ -372,31 +373,39 @@ func SkipValue(r io.Reader, buf []byte, pos, n int) (newPos, newN int, err error
 			return pos, n, fmt.Errorf("read error at position %d: %w", pos, err)
 		}
 		if n == 0 {
-			return pos, n, fmt.Errorf("expected JSON value at position %d: %w", pos, io.EOF)
+			return pos, n, jsonTextError(*base+pos, "value", "EOF", io.EOF)
 		}
+		*base += pos
 		pos = 0
 	}
 
+	// Count the value, containers count their brackets
+	if err := countJSONTextToken(*base+pos, limits, tokens); err != nil {
+		return pos, n, err
+	}
+
 	// Handle value based on first character
 	c := buf[pos]
 	switch c {
 	case '"':
 		// Handle string
-		_, pos, err = ReadString(r, buf, pos, n)
-		if err != nil {
-			return pos, n, fmt.Errorf("invalid string at position %d: %w", pos, err)
-		}
-		return pos, n, nil
+		return scanJSONTextString(r, buf, pos, n, base, nil, limits.MaxStringLength)
 
 	case '{', '[':
-		// Handle object or array
-		opening := c
-		depth := 1
+		// Handle object or array, closing holds the bracket expected for each open container
+		// and want the token expected next: a key, a value, ':' or ','
+		// an upper case state also allows closing an empty container
+		closing := []byte{'}'}
+		want := byte('K')
+		if c == '[' {
+			closing[0] = ']'
+			want = 'V'
+		}
 		pos++
 
-		for depth > 0 {
+		for len(closing) > 0 {
 			// Skip whitespace
-			pos, n, err = ReadUntilNonWhitespace(r, buf, pos, n)
+			pos, n, err = readUntilNonWhitespace(r, buf, pos, n, base)
 			if err != nil {
 				return pos, n, fmt.Errorf("error in nested structure at position %d: %w", pos, err)
 			}
@@ -408,129 +417,394 @@ func SkipValue(r io.Reader, buf []byte, pos, n int) (newPos, newN int, err error
 					return pos, n, fmt.Errorf("read error at position %d: %w", pos, err)
 				}
 				if n == 0 {
-					return pos, n, fmt.Errorf("unclosed %c at position %d: %w", opening, pos, io.EOF)
+					return pos, n, jsonTextError(*base+pos, jsonQuoteByte(closing[len(closing)-1]), "EOF", io.EOF)
 				}
+				*base += pos
 				pos = 0
 			}
 
 			c = buf[pos]
-			if c == '"' {
+			object := closing[len(closing)-1] == '}'
+
+			switch {
+			case c == '}' || c == ']':
+				// Close the innermost container, after an element or when it is empty
+				if c != closing[len(closing)-1] || want != ',' && want != 'K' && want != 'V' {
+					return pos, n, jsonTextError(*base+pos, jsonTextWant(want, closing[len(closing)-1]), jsonQuoteByte(c), nil)
+				}
+				closing = closing[:len(closing)-1]
+				if err := countJSONTextToken(*base+pos, limits, tokens); err != nil {
+					return pos, n, err
+				}
+				want = ','
+				pos++
+			case c == ',' && want == ',':
+				// Separator between members and elements
+				want = 'v'
+				if object {
+					want = 'k'
+				}
+				pos++
+			case c == ':' && want == ':':
+				// Separator between a key and its value
+				want = 'v'
+				pos++
+			case c == '"' && (want == 'k' || want == 'K'):
+				// Skip key
+				if err := countJSONTextToken(*base+pos, limits, tokens); err != nil {
+					return pos, n, err
+				}
+				pos, n, err = scanJSONTextString(r, buf, pos, n, base, nil, limits.MaxStringLength)
+				if err != nil {
+					return pos, n, err
+				}
+				want = ':'
+			case want != 'v' && want != 'V':
+				return pos, n, jsonTextError(*base+pos, jsonTextWant(want, closing[len(closing)-1]), jsonQuoteByte(c), nil)
+			case c == '"':
 				// Skip string
-				_, pos, err = ReadString(r, buf, pos, n)
+				if err := countJSONTextToken(*base+pos, limits, tokens); err != nil {
+					return pos, n, err
+				}
+				pos, n, err = scanJSONTextString(r, buf, pos, n, base, nil, limits.MaxStringLength)
 				if err != nil {
-					return pos, n, fmt.Errorf("invalid string in %c at position %d: %w", opening, pos, err)
+					return pos, n, err
 				}
-			} else if c == '{' || c == '[' {
-				// Increase depth for nested object or array
-				depth++
-				pos++
-			} else if c == '}' || c == ']' {
-				// Decrease depth and check matching
-				if (c == '}' && opening != '{') || (c == ']' && opening != '[') {
-					return pos, n, fmt.Errorf("mismatched closing %c for %c at position %d", c, opening, pos)
+				want = ','
+			case c == '{' || c == '[':
+				// Open a nested object or array
+				if c == '{' {
+					closing = append(closing, '}')
+					want = 'K'
+				} else {
+					closing = append(closing, ']')
+					want = 'V'
+				}
+				if len(closing) > maxJSONDepth(*limits) {
+					return pos, n, &JSONSyntaxError{Offset: *base + pos, Err: ErrJSONTooDeep}
+				}
+				if err := countJSONTextToken(*base+pos, limits, tokens); err != nil {
+					return pos, n, err
 				}
-				depth--
 				pos++
-			} else {
+			default:
 				// Skip other values (number, true, false, null)
-				_, pos, err = SkipValue(r, buf, pos, n)
+				pos, n, err = skipValue(r, buf, pos, n, base, limits, tokens)
 				if err != nil {
-					return pos, n, fmt.Errorf("invalid value in %c at position %d: %w", opening, pos, err)
+					return pos, n, err
 				}
+				want = ','
 			}
 		}
 		return pos, n, nil
 
 	case 't':
 		// Handle true
-		if pos+3 >= n {
-			// Move remaining bytes to start and read more
-			if pos < n {
-				copy(buf[0:], buf[pos:n])
-				n -= pos
-				pos = 0
-			} else {
-				n = 0
-				pos = 0
-			}
-			n2, err := r.Read(buf[n:])
-			if err != nil && err != io.EOF {
-				return pos, n, fmt.Errorf("read error at position %d: %w", pos, err)
-			}
-			n += n2
-			if pos+3 >= n {
-				return pos, n, fmt.Errorf("incomplete true at position %d: %w", pos, io.EOF)
-			}
-		}
-		if string(buf[pos:pos+4]) != "true" {
-			return pos, n, fmt.Errorf("expected true at position %d, got %q", pos, string(buf[pos:pos+4]))
-		}
-		pos += 4
-		return pos, n, nil
+		return skipJSONTextLiteral(r, buf, pos, n, base, "true")
 
 	case 'f':
 		// Handle false
-		if pos+4 >= n {
-			if pos < n {
-				copy(buf[0:], buf[pos:n])
-				n -= pos
-				pos = 0
-			} else {
-				n = 0
-				pos = 0
-			}
+		return skipJSONTextLiteral(r, buf, pos, n, base, "false")
+
+	case 'n':
+		// Handle null
+		return skipJSONTextLiteral(r, buf, pos, n, base, "null")
+
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		// Handle number
+		_, pos, n, err = readJSONTextNumber(r, buf, pos, n, base)
+		if err != nil {
+			return pos, n, err
+		}
+		return pos, n, nil
+
+	default:
+		return pos, n, jsonTextError(*base+pos, "value", jsonQuoteByte(c), nil)
+	}
+}
+
+// skipJSONTextLiteral skips the literal lit starting at pos.
+// Readers may return fewer bytes than asked, so it keeps reading until the whole literal is buffered.
+func skipJSONTextLiteral(r io.Reader, buf []byte, pos, n int, base *int, lit string) (int, int, error) {
+
+	if pos+len(lit) > n {
+		// Move remaining bytes to start and read more
+		copy(buf, buf[pos:n])
+		n -= pos
+		*base += pos
+		pos = 0
+
+		for n < len(lit) && n < len(buf) {
 			n2, err := r.Read(buf[n:])
-			if err != nil && err != io.EOF {
-				return pos, n, fmt.Errorf("read error at position %d: %w", pos, err)
-			}
 			n += n2
-			if pos+4 >= n {
-				return pos, n, fmt.Errorf("incomplete false at position %d: %w", pos, io.EOF)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return pos, n, fmt.Errorf("read error at position %d: %w", *base+n, err)
 			}
 		}
-		if string(buf[pos:pos+5]) != "false" {
-			return pos, n, fmt.Errorf("expected false at position %d, got %q", pos, string(buf[pos:pos+5]))
+
+		if n < len(lit) {
+			if string(buf[:n]) != lit[:n] {
+				return pos, n, jsonTextError(*base, lit, strconv.Quote(string(buf[:n])), nil)
+			}
+			return pos, n, jsonTextError(*base, lit, "EOF", io.EOF)
 		}
-		pos += 5
-		return pos, n, nil
+	}
 
-	case 'n':
-		// Handle null
-		if pos+3 >= n {
-			if pos < n {
-				copy(buf[0:], buf[pos:n])
-				n -= pos
-				pos = 0
-			} else {
-				n = 0
-				pos = 0
+	if string(buf[pos:pos+len(lit)]) != lit {
+		return pos, n, jsonTextError(*base+pos, lit, strconv.Quote(string(buf[pos:pos+len(lit)])), nil)
+	}
+
+	return pos + len(lit), n, nil
+}
+
+// DefaultRedactKeys are the key fragments RedactJSON hides when no keys are given.
+var DefaultRedactKeys = []string{"password", "token", "secret"}
+
+// JSONFormatOptions controls how ReformatJSON writes a document.
+type JSONFormatOptions struct {
+	Prefix string // written at the start of every line after the first
+	Indent string // written once per nesting level, empty Prefix and Indent minify the document
+	// RedactKeys hides the values of members whose key contains one of them, ignoring case
+	RedactKeys []string
+	// Placeholder is the string written instead of a redacted value, "[REDACTED]" when empty
+	Placeholder string
+}
+
+// ReformatJSON copies the JSON document read from src to dst, indented or minified as options say.
+// Tokens are copied as they are read, so memory is bounded by the longest token and the nesting depth.
+// Strings and numbers keep their original text.
+func ReformatJSON(dst io.Writer, src io.Reader, options JSONFormatOptions) error {
+
+	r := CreateJSONReader(src)
+	defer ReleaseJSONReader(r)
+
+	placeholder := options.Placeholder
+
+	if placeholder == "" {
+		placeholder = "[REDACTED]"
+	}
+
+	quoted, err := encodeJSONValue(placeholder)
+
+	if err != nil {
+		return err
+	}
+
+	f := &jsonFormatter{
+		w:           bufio.NewWriter(dst),
+		prefix:      options.Prefix,
+		indent:      options.Indent,
+		pretty:      options.Prefix != "" || options.Indent != "",
+		placeholder: quoted,
+	}
+
+	for _, key := range options.RedactKeys {
+		f.redact = append(f.redact, strings.ToLower(key))
+	}
+
+	if err := f.format(r); err != nil {
+		return err
+	}
+
+	return f.w.Flush()
+}
+
+// IndentJSON copies the JSON document read from src to dst with each element on its own line.
+func IndentJSON(dst io.Writer, src io.Reader, prefix, indent string) error {
+	return ReformatJSON(dst, src, JSONFormatOptions{Prefix: prefix, Indent: indent})
+}
+
+// MinifyJSON copies the JSON document read from src to dst without insignificant whitespace.
+func MinifyJSON(dst io.Writer, src io.Reader) error {
+	return ReformatJSON(dst, src, JSONFormatOptions{})
+}
+
+// RedactJSON copies the minified JSON document read from src to dst, hiding the values of members
+// whose key contains one of keys, or one of DefaultRedactKeys when none are given.
+func RedactJSON(dst io.Writer, src io.Reader, keys ...string) error {
+	if len(keys) == 0 {
+		keys = DefaultRedactKeys
+	}
+	return ReformatJSON(dst, src, JSONFormatOptions{RedactKeys: keys})
+}
+
+// jsonFormatter writes the tokens of a document with the layout of ReformatJSON.
+type jsonFormatter struct {
+	w           *bufio.Writer
+	prefix      string
+	indent      string
+	pretty      bool
+	redact      []string
+	placeholder []byte
+	items       []bool // whether each open container has an element yet
+	afterKey    bool
+}
+
+// format copies the tokens of the document read by r.
+func (f *jsonFormatter) format(r *JSONReader) error {
+
+	for {
+		tok, err := r.Next()
+
+		if err == io.EOF {
+			return r.syntaxError("value", "EOF")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch tok.Kind {
+		case JSONTokenObjectStart, JSONTokenArrayStart:
+			f.beforeValue()
+			f.w.WriteByte(tok.Raw[0])
+			f.items = append(f.items, false)
+		case JSONTokenObjectEnd, JSONTokenArrayEnd:
+			last := len(f.items) - 1
+			if f.items[last] {
+				f.newline(last)
 			}
-			n2, err := r.Read(buf[n:])
-			if err != nil && err != io.EOF {
-				return pos, n, fmt.Errorf("read error at position %d: %w", pos, err)
+			f.items = f.items[:last]
+			f.w.WriteByte(tok.Raw[0])
+		case JSONTokenKey:
+			f.beforeValue()
+			f.w.Write(tok.Raw)
+			f.w.WriteByte(_ColonChar)
+			if f.pretty {
+				f.w.WriteByte(' ')
 			}
-			n += n2
-			if pos+3 >= n {
-				return pos, n, fmt.Errorf("incomplete null at position %d: %w", pos, io.EOF)
+			f.afterKey = true
+			if f.redacted(tok.Value) {
+				if err := skipJSONTokens(r); err != nil {
+					return err
+				}
+				f.w.Write(f.placeholder)
+				f.afterKey = false
 			}
+		default:
+			f.beforeValue()
+			f.w.Write(tok.Raw)
 		}
-		if string(buf[pos:pos+4]) != "null" {
-			return pos, n, fmt.Errorf("expected null at position %d, got %q", pos, string(buf[pos:pos+4]))
+
+		if len(f.items) == 0 {
+			return r.ExpectEOF()
 		}
-		pos += 4
-		return pos, n, nil
+	}
+}
+
+// beforeValue writes the separator and line break before a key, or a value that does not follow a key.
+func (f *jsonFormatter) beforeValue() {
+
+	if f.afterKey {
+		f.afterKey = false
+		return
+	}
+
+	last := len(f.items) - 1
+
+	if last < 0 {
+		return
+	}
+
+	if f.items[last] {
+		f.w.WriteByte(_CommaChar)
+	}
+
+	f.items[last] = true
+	f.newline(len(f.items))
+}
+
+// newline starts a line at depth when indenting.
+func (f *jsonFormatter) newline(depth int) {
+
+	if !f.pretty {
+		return
+	}
+
+	f.w.WriteByte('\n')
+	f.w.WriteString(f.prefix)
+
+	for i := 0; i < depth; i++ {
+		f.w.WriteString(f.indent)
+	}
+}
+
+// redacted reports whether the value of key is hidden.
+func (f *jsonFormatter) redacted(key string) bool {
+
+	if len(f.redact) == 0 {
+		return false
+	}
+
+	key = strings.ToLower(key)
+
+	for _, k := range f.redact {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipJSONTokens reads the tokens of the next value of r.
+func skipJSONTokens(r *JSONReader) error {
+
+	depth := 0
+
+	for {
+		tok, err := r.Next()
 
-	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		// Handle number
-		_, pos, err = ReadNumber(r, buf, pos, n)
 		if err != nil {
-			return pos, n, fmt.Errorf("invalid number at position %d: %w", pos, err)
+			if err == io.EOF {
+				return r.syntaxError("value", "EOF")
+			}
+			return err
 		}
-		return pos, n, nil
 
+		switch tok.Kind {
+		case JSONTokenObjectStart, JSONTokenArrayStart:
+			depth++
+		case JSONTokenObjectEnd, JSONTokenArrayEnd:
+			depth--
+		case JSONTokenKey:
+			continue
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// jsonTextWant describes the token skipValue expects in state want inside a container closed by closing.
+func jsonTextWant(want, closing byte) string {
+	switch want {
+	case 'K':
+		return "string or '}'"
+	case 'k':
+		return "string"
+	case ':':
+		return "':'"
+	case ',':
+		return "',' or " + jsonQuoteByte(closing)
+	case 'V':
+		return "value or ']'"
 	default:
-		return pos, n, fmt.Errorf("invalid JSON value starting with %q at position %d", c, pos)
+		return "value"
+	}
+}
+
+// countJSONTextToken counts a token at offset and checks MaxTokens.
+func countJSONTextToken(offset int, limits *JSONLimits, tokens *int) error {
+	*tokens++
+	if limits.MaxTokens > 0 && *tokens > limits.MaxTokens {
+		return &JSONSyntaxError{Offset: offset, Err: ErrJSONTooManyTokens}
 	}
+	return nil
 }
 
 // parseUint64
@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestReadUntilNonWhitespace(t *testing.T) {
@@ -95,3 +98,148 @@ func TestReadUntilNonWhitespace(t *testing.T) {
 		})
 	}
 }
+
+func TestSkipValueStructure(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{input: `{"a": [1, {"b": null}, []], "c": {}}`, valid: true},
+		{input: `[[], {}, "x", -1.5e3, true]`, valid: true},
+		{input: `[1 2]`},
+		{input: `{"a" 1}`},
+		{input: `{"a": 1,}`},
+		{input: `[1,]`},
+		{input: `[,1]`},
+		{input: `{1: 2}`},
+		{input: `{"a": 1 "b": 2}`},
+		{input: `{"a": 1]`},
+		{input: `{"a"}`},
+	}
+
+	for _, tt := range tests {
+		pos, _, err := SkipValue(strings.NewReader(tt.input), make([]byte, 8), 0, 0)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+		}
+		var syntaxErr *JSONSyntaxError
+		if !tt.valid && !errors.As(err, &syntaxErr) {
+			t.Errorf("%s: expected *JSONSyntaxError, got %v at %d", tt.input, err, pos)
+		}
+	}
+}
+
+func TestSkipValueLiterals(t *testing.T) {
+	tests := []struct {
+		input string
+		end   int
+		valid bool
+	}{
+		{input: `true`, end: 4, valid: true},
+		{input: `false`, end: 5, valid: true},
+		{input: `null`, end: 4, valid: true},
+		{input: `[true, false, null]`, end: 19, valid: true},
+		{input: `tru`},
+		{input: `nul`},
+		{input: `trux`},
+		{input: `[fals]`},
+	}
+
+	for _, tt := range tests {
+		// a reader may return a single byte at a time, so literals are completed with more reads
+		base := 0
+		pos, _, err := skipValue(iotest.OneByteReader(strings.NewReader(tt.input)), make([]byte, 8), 0, 0, &base, &JSONLimits{}, new(int))
+		if tt.valid && (err != nil || base+pos != tt.end) {
+			t.Errorf("%s: expected end %d, got %d %v", tt.input, tt.end, base+pos, err)
+		}
+		var syntaxErr *JSONSyntaxError
+		if !tt.valid && !errors.As(err, &syntaxErr) {
+			t.Errorf("%s: expected *JSONSyntaxError, got %v", tt.input, err)
+		}
+	}
+}
+
+func TestReadString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{input: `"plain" `, want: "plain"},
+		{input: `"a\n\"b\/"`, want: "a\n\"b/"},
+		{input: `"caf\u00e9 \uD83D\uDE00"`, want: "caf\u00e9 \U0001F600"},
+		{input: `"\uD83Dx"`, err: true},
+		{input: `"\uDE00"`, err: true},
+		{input: `"\u12"`, err: true},
+		{input: `"\q"`, err: true},
+		{input: `"open`, err: true},
+	}
+
+	for _, tt := range tests {
+		// a small buffer refills in the middle of escapes
+		got, _, err := ReadString(strings.NewReader(tt.input), make([]byte, 4), 0, 0)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("%s: expected %q error %v, got %q %v", tt.input, tt.want, tt.err, got, err)
+		}
+	}
+}
+
+func TestReformatJSON(t *testing.T) {
+	docs := []string{
+		`{"a": [1, 2.50, {"b": null, "c": []}], "d": {}, "e": "xé\"y", "f": [[true], [false]]}`,
+		`[]`,
+		`"top"`,
+		` [ { } , [ ] , -0.5e+3 ] `,
+	}
+
+	for _, doc := range docs {
+		var want, got bytes.Buffer
+
+		json.Indent(&want, []byte(strings.TrimSpace(doc)), "> ", "\t")
+		if err := IndentJSON(&got, iotest.OneByteReader(strings.NewReader(doc)), "> ", "\t"); err != nil {
+			t.Fatalf("%s: %v", doc, err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("indent %s: expected\n%s\ngot\n%s", doc, want.String(), got.String())
+		}
+
+		want.Reset()
+		got.Reset()
+		json.Compact(&want, []byte(doc))
+		if err := MinifyJSON(&got, strings.NewReader(doc)); err != nil {
+			t.Fatalf("%s: %v", doc, err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("minify %s: expected %s, got %s", doc, want.String(), got.String())
+		}
+	}
+
+	for _, bad := range []string{``, `{"a": }`, `[1] [2]`, `{"a" 1}`} {
+		var syntaxErr *JSONSyntaxError
+		if err := MinifyJSON(io.Discard, strings.NewReader(bad)); !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected *JSONSyntaxError, got %v", bad, err)
+		}
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	doc := `{"user": "bob", "Password": "hunter2", "auth": {"access_token": {"v": [1, {"x": 2}]}, "n": 1}, "list": [{"secret": null}], "Tokens": 3}`
+	want := `{"user":"bob","Password":"[REDACTED]","auth":{"access_token":"[REDACTED]","n":1},"list":[{"secret":"[REDACTED]"}],"Tokens":"[REDACTED]"}`
+
+	var got bytes.Buffer
+	if err := RedactJSON(&got, strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want {
+		t.Errorf("expected %s, got %s", want, got.String())
+	}
+
+	got.Reset()
+	err := ReformatJSON(&got, strings.NewReader(`{"user": "bob", "pin": [1]}`), JSONFormatOptions{Indent: "  ", RedactKeys: []string{"PIN"}, Placeholder: "***"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"user\": \"bob\",\n  \"pin\": \"***\"\n}"; got.String() != want {
+		t.Errorf("expected %s, got %s", want, got.String())
+	}
+}
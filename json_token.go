@@ -0,0 +1,215 @@
+package utils
+
+// JSONTokenKind is the kind of a JSON token
+type JSONTokenKind uint8
+
+const (
+	JSONTokenInvalid JSONTokenKind = iota
+	JSONTokenObjectStart
+	JSONTokenObjectEnd
+	JSONTokenArrayStart
+	JSONTokenArrayEnd
+	JSONTokenKey
+	JSONTokenString
+	JSONTokenNumber
+	JSONTokenBool
+	JSONTokenNull
+)
+
+// String returns the name of the token kind
+func (k JSONTokenKind) String() string {
+	switch k {
+	case JSONTokenObjectStart:
+		return "ObjectStart"
+	case JSONTokenObjectEnd:
+		return "ObjectEnd"
+	case JSONTokenArrayStart:
+		return "ArrayStart"
+	case JSONTokenArrayEnd:
+		return "ArrayEnd"
+	case JSONTokenKey:
+		return "Key"
+	case JSONTokenString:
+		return "String"
+	case JSONTokenNumber:
+		return "Number"
+	case JSONTokenBool:
+		return "Bool"
+	case JSONTokenNull:
+		return "Null"
+	default:
+		return "Invalid"
+	}
+}
+
+// JSONToken is a single token of a JSON document
+// Raw refers to the input and is only valid as long as the input is
+type JSONToken struct {
+	Kind   JSONTokenKind
+	Raw    []byte // raw bytes of the token, including quotes for Key and String
+	Value  string // unescaped text of Key and String tokens
+	Offset int    // byte offset of the token in the input
+	Line   int
+	Column int
+}
+
+// Bool returns the value of a Bool token
+func (t JSONToken) Bool() bool {
+	return len(t.Raw) > 0 && t.Raw[0] == 't'
+}
+
+// jsonKindOf returns the kind of the value starting with c
+func jsonKindOf(c byte) JSONTokenKind {
+	switch c {
+	case _BraceLeft:
+		return JSONTokenObjectStart
+	case _BracketLeft:
+		return JSONTokenArrayStart
+	case _QuoteChar:
+		return JSONTokenString
+	case 't', 'f':
+		return JSONTokenBool
+	case 'n':
+		return JSONTokenNull
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return JSONTokenNumber
+	default:
+		return JSONTokenInvalid
+	}
+}
+
+const (
+	jsonStateValue      uint8 = iota // expecting a value
+	jsonStateValueOrEnd              // after '[', expecting a value or ']'
+	jsonStateKeyOrEnd                // after '{', expecting a key or '}'
+	jsonStateKey                     // after ',' in an object, expecting a key
+	jsonStateColon                   // after a key, expecting ':'
+	jsonStateCommaOrEnd              // after a value in a container, expecting ',' or the closing char
+	jsonStateDone                    // a top-level value is complete
+)
+
+// jsonTokenizer tracks the structure of a document for pull-based tokenizing
+// it decides what the next byte means and leaves the reading to the caller
+type jsonTokenizer struct {
+	stack   []byte
+	state   uint8
+	lenient bool // single-quoted strings and unquoted keys, see JSONLexer.SetLenient
+}
+
+// reset prepares the tokenizer for a new document
+func (t *jsonTokenizer) reset() {
+	t.stack = t.stack[:0]
+	t.state = jsonStateValue
+	t.lenient = false
+}
+
+// atEOF reports whether the end of input is valid in the current state
+func (t *jsonTokenizer) atEOF() bool {
+	return len(t.stack) == 0 && (t.state == jsonStateValue || t.state == jsonStateDone)
+}
+
+// step inspects the next non-whitespace byte c
+// skip is true if c is a separator to consume before calling step again
+// otherwise kind is the token starting at c, or expected describes what was expected instead
+func (t *jsonTokenizer) step(c byte) (kind JSONTokenKind, skip bool, expected string) {
+	switch t.state {
+	case jsonStateColon:
+		if c == _ColonChar {
+			t.state = jsonStateValue
+			return JSONTokenInvalid, true, ""
+		}
+		return JSONTokenInvalid, false, "':'"
+	case jsonStateCommaOrEnd:
+		top := t.stack[len(t.stack)-1]
+		if c == _CommaChar {
+			if top == _BraceLeft {
+				t.state = jsonStateKey
+			} else {
+				t.state = jsonStateValue
+			}
+			return JSONTokenInvalid, true, ""
+		}
+		if top == _BraceLeft {
+			if c == _BraceRight {
+				return t.pop(JSONTokenObjectEnd), false, ""
+			}
+			return JSONTokenInvalid, false, "',' or '}'"
+		}
+		if c == _BracketRight {
+			return t.pop(JSONTokenArrayEnd), false, ""
+		}
+		return JSONTokenInvalid, false, "',' or ']'"
+	case jsonStateKeyOrEnd, jsonStateKey:
+		if c == _BraceRight && t.state == jsonStateKeyOrEnd {
+			return t.pop(JSONTokenObjectEnd), false, ""
+		}
+		if c == _QuoteChar || t.lenient && (c == '\'' || isJSONIdentStart(c)) {
+			t.state = jsonStateColon
+			return JSONTokenKey, false, ""
+		}
+		return JSONTokenInvalid, false, "string"
+	}
+
+	if c == _BracketRight && t.state == jsonStateValueOrEnd {
+		return t.pop(JSONTokenArrayEnd), false, ""
+	}
+
+	switch c {
+	case _BraceLeft:
+		t.stack = append(t.stack, _BraceLeft)
+		t.state = jsonStateKeyOrEnd
+		return JSONTokenObjectStart, false, ""
+	case _BracketLeft:
+		t.stack = append(t.stack, _BracketLeft)
+		t.state = jsonStateValueOrEnd
+		return JSONTokenArrayStart, false, ""
+	}
+
+	kind = jsonKindOf(c)
+	if kind == JSONTokenInvalid && t.lenient && c == '\'' {
+		kind = JSONTokenString
+	}
+	if kind == JSONTokenInvalid {
+		return JSONTokenInvalid, false, "value"
+	}
+
+	t.afterValue()
+	return kind, false, ""
+}
+
+// expected describes what the current state expects, for errors at the end of input
+func (t *jsonTokenizer) expected() string {
+	switch t.state {
+	case jsonStateValueOrEnd:
+		return "value or ']'"
+	case jsonStateKeyOrEnd:
+		return "string or '}'"
+	case jsonStateKey:
+		return "string"
+	case jsonStateColon:
+		return "':'"
+	case jsonStateCommaOrEnd:
+		if t.stack[len(t.stack)-1] == _BraceLeft {
+			return "',' or '}'"
+		}
+		return "',' or ']'"
+	default:
+		return "value"
+	}
+}
+
+// pop closes the innermost container and returns kind
+func (t *jsonTokenizer) pop(kind JSONTokenKind) JSONTokenKind {
+	t.stack = t.stack[:len(t.stack)-1]
+	t.afterValue()
+	return kind
+}
+
+// afterValue moves to the state following a complete value
+func (t *jsonTokenizer) afterValue() {
+	if len(t.stack) == 0 {
+		t.state = jsonStateDone
+	} else {
+		t.state = jsonStateCommaOrEnd
+	}
+}
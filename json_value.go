@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// JSONNumberMode selects the Go type ReadValue decodes numbers to
+type JSONNumberMode uint8
+
+const (
+	// JSONNumberFloat64 decodes numbers to float64, as encoding/json does
+	JSONNumberFloat64 JSONNumberMode = iota
+	// JSONNumberJSON keeps numbers as json.Number
+	JSONNumberJSON
+	// JSONNumberInt64 decodes integer literals that fit in an int64 to int64 and other numbers to float64
+	JSONNumberInt64
+)
+
+// JSONValueOptions controls how ReadValue builds values
+type JSONValueOptions struct {
+	Numbers JSONNumberMode
+	// NumberFunc builds numbers from their literal bytes instead of Numbers when set
+	// The slice is only valid during the call
+	NumberFunc func(raw []byte) (any, error)
+	// Ordered decodes objects to JSONObject, keeping key order and duplicate keys
+	Ordered bool
+}
+
+// JSONMember is a member of a JSONObject
+type JSONMember struct {
+	Key   string
+	Value any
+}
+
+// JSONObject is a JSON object that keeps the order of its members
+type JSONObject []JSONMember
+
+// Get returns the value of the last member named key
+func (o JSONObject) Get(key string) (any, bool) {
+
+	for i := len(o) - 1; i >= 0; i-- {
+		if o[i].Key == key {
+			return o[i].Value, true
+		}
+	}
+
+	return nil, false
+}
+
+// MarshalJSON encodes the members in order
+func (o JSONObject) MarshalJSON() ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	buf.WriteByte(_BraceLeft)
+
+	for i, m := range o {
+
+		if i > 0 {
+			buf.WriteByte(_CommaChar)
+		}
+
+		key, err := encodeJSONValue(m.Key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := encodeJSONValue(m.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(_ColonChar)
+		buf.Write(value)
+	}
+
+	buf.WriteByte(_BraceRight)
+
+	return buf.Bytes(), nil
+}
+
+// SetValueOptions sets how ReadValue builds values
+func (l *JSONLexer) SetValueOptions(options JSONValueOptions) {
+	l.valueOptions = options
+}
+
+// SetValueOptions sets how ReadValue builds values
+func (r *JSONReader) SetValueOptions(options JSONValueOptions) {
+	r.valueOptions = options
+}
+
+// ReadValue reads the next JSON value into map[string]any, []any, string, bool, nil or a number
+// Numbers and objects are built as set by SetValueOptions, by default like encoding/json
+func (l *JSONLexer) ReadValue() (any, error) {
+	return readJSONValue(l, &l.valueOptions, 0)
+}
+
+// ReadValue reads the next JSON value into map[string]any, []any, string, bool, nil or a number
+// Numbers and objects are built as set by SetValueOptions, by default like encoding/json
+func (r *JSONReader) ReadValue() (any, error) {
+	return readJSONValue(r, &r.valueOptions, 0)
+}
+
+// jsonValueScanner is a JSONScanner with the internals ReadValue and ReadObject need
+type jsonValueScanner interface {
+	JSONScanner
+	checkDepth(depth int) error
+	inputOffset() int
+	syntaxError(expected, got string) *JSONSyntaxError
+	syntaxErrorMsg(msg string, err error) *JSONSyntaxError
+	isLenient() bool
+}
+
+// readJSONValue reads the next value of s at depth
+func readJSONValue(s jsonValueScanner, options *JSONValueOptions, depth int) (any, error) {
+
+	s.SkipWhitespace()
+
+	switch c := s.Peek(); jsonKindOf(c) {
+	case JSONTokenObjectStart:
+		return readJSONObject(s, options, depth+1)
+	case JSONTokenArrayStart:
+		if err := s.checkDepth(depth + 1); err != nil {
+			return nil, err
+		}
+		s.Advance()
+		s.SkipWhitespace()
+		values := []any{}
+		if s.Peek() == _BracketRight {
+			s.Advance()
+			return values, nil
+		}
+		for {
+			v, err := readJSONValue(s, options, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			s.SkipWhitespace()
+			if s.Peek() == _BracketRight {
+				s.Advance()
+				return values, nil
+			}
+			if err := s.Expect(_CommaChar); err != nil {
+				return nil, err
+			}
+		}
+	case JSONTokenString:
+		return s.ReadString()
+	case JSONTokenNumber:
+		return readJSONValueNumber(s, options)
+	case JSONTokenBool:
+		return s.ReadBool()
+	case JSONTokenNull:
+		return nil, s.ReadNull()
+	default:
+		if c == '\'' && s.isLenient() {
+			return s.ReadString()
+		}
+		if c == 0 {
+			return nil, s.syntaxError("value", "EOF")
+		}
+		return nil, s.syntaxError("value", jsonQuoteByte(c))
+	}
+}
+
+// readJSONObject reads an object into a map or a JSONObject
+func readJSONObject(s jsonValueScanner, options *JSONValueOptions, depth int) (any, error) {
+
+	if err := s.checkDepth(depth); err != nil {
+		return nil, err
+	}
+
+	s.Advance()
+	s.SkipWhitespace()
+
+	var ordered JSONObject
+	var members map[string]any
+
+	if options.Ordered {
+		ordered = JSONObject{}
+	} else {
+		members = map[string]any{}
+	}
+
+	if s.Peek() != _BraceRight {
+		for {
+			key, err := s.ReadKey()
+
+			if err != nil {
+				return nil, err
+			}
+
+			if err := s.Expect(_ColonChar); err != nil {
+				return nil, err
+			}
+
+			v, err := readJSONValue(s, options, depth)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if options.Ordered {
+				ordered = append(ordered, JSONMember{Key: key, Value: v})
+			} else {
+				members[key] = v
+			}
+
+			s.SkipWhitespace()
+
+			if s.Peek() == _BraceRight {
+				break
+			}
+
+			if err := s.Expect(_CommaChar); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	s.Advance()
+
+	if options.Ordered {
+		return ordered, nil
+	}
+
+	return members, nil
+}
+
+// readJSONValueNumber reads a number as set by options
+func readJSONValueNumber(s jsonValueScanner, options *JSONValueOptions) (any, error) {
+
+	raw, err := s.ReadNumberRaw()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if options.NumberFunc != nil {
+		v, err := options.NumberFunc(raw)
+		if err != nil {
+			return nil, s.syntaxErrorMsg("invalid number", err)
+		}
+		return v, nil
+	}
+
+	switch options.Numbers {
+	case JSONNumberJSON:
+		return json.Number(raw), nil
+	case JSONNumberInt64:
+		if bytes.IndexAny(raw, ".eE") < 0 {
+			if n, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	}
+
+	f, err := strconv.ParseFloat(string(raw), 64)
+
+	if err != nil {
+		return nil, s.syntaxErrorMsg("invalid number", err)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadValue(t *testing.T) {
+	input := `{"a": [1, -2.5, 9007199254740993, 1e2], "b": {"x": "y", "n": null}, "c": true, "d": []}`
+
+	ratFunc := func(raw []byte) (any, error) {
+		r, ok := new(big.Rat).SetString(string(raw))
+		if !ok {
+			return nil, errors.New("bad rat")
+		}
+		return r, nil
+	}
+
+	tests := []struct {
+		name    string
+		options JSONValueOptions
+		numbers []any
+	}{
+		{name: "float64", numbers: []any{1.0, -2.5, 9007199254740992.0, 100.0}},
+		{name: "json.Number", options: JSONValueOptions{Numbers: JSONNumberJSON}, numbers: []any{json.Number("1"), json.Number("-2.5"), json.Number("9007199254740993"), json.Number("1e2")}},
+		{name: "int64", options: JSONValueOptions{Numbers: JSONNumberInt64}, numbers: []any{int64(1), -2.5, int64(9007199254740993), 100.0}},
+		{name: "func", options: JSONValueOptions{NumberFunc: ratFunc}, numbers: []any{big.NewRat(1, 1), big.NewRat(-5, 2), new(big.Rat).SetInt64(9007199254740993), big.NewRat(100, 1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := map[string]any{
+				"a": tt.numbers,
+				"b": map[string]any{"x": "y", "n": nil},
+				"c": true,
+				"d": []any{},
+			}
+
+			l := CreateJSONLexer([]byte(input))
+			defer ReleaseJSONLexer(l)
+			l.SetValueOptions(tt.options)
+
+			r := CreateJSONReader(iotest.OneByteReader(strings.NewReader(input)))
+			defer ReleaseJSONReader(r)
+			r.SetValueOptions(tt.options)
+
+			for _, s := range []JSONScanner{l, r} {
+				got, err := s.ReadValue()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%T: expected %v, got %v", s, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReadValueLenient(t *testing.T) {
+	input := `// settings
+	{a: 'x', "b": ['it\'s', "y",], /* c */ c: {d: 'z'},}`
+
+	l := CreateJSONLexer([]byte(input))
+	defer ReleaseJSONLexer(l)
+	l.SetLenient(true)
+
+	got, err := l.ReadValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{"a": "x", "b": []any{"it's", "y"}, "c": map[string]any{"d": "z"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// single quotes are only accepted in lenient mode
+	strict := CreateJSONLexer([]byte(`{"a": 'x'}`))
+	defer ReleaseJSONLexer(strict)
+
+	var syntaxErr *JSONSyntaxError
+	if _, err := strict.ReadValue(); !errors.As(err, &syntaxErr) {
+		t.Errorf("expected *JSONSyntaxError, got %v", err)
+	}
+}
+
+func TestReadValueOrdered(t *testing.T) {
+	input := `{"z":1,"a":{"y":[{"k":"v","b":false}],"x":null},"z":"dup"}`
+
+	l := CreateJSONLexer([]byte(input))
+	defer ReleaseJSONLexer(l)
+	l.SetValueOptions(JSONValueOptions{Numbers: JSONNumberJSON, Ordered: true})
+
+	v, err := l.ReadValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o, ok := v.(JSONObject)
+	if !ok {
+		t.Fatalf("expected JSONObject, got %T", v)
+	}
+	if z, _ := o.Get("z"); z != "dup" {
+		t.Errorf("expected last duplicate, got %v", z)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != input {
+		t.Errorf("expected %s, got %s", input, out)
+	}
+}
+
+func TestReadValueErrors(t *testing.T) {
+	tests := []string{`{"a" 1}`, `[1,]`, `[`, `{"a": 1e400}`, `x`, ``}
+
+	for _, input := range tests {
+		l := CreateJSONLexer([]byte(input))
+		var syntaxErr *JSONSyntaxError
+		if _, err := l.ReadValue(); !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected *JSONSyntaxError, got %v", input, err)
+		}
+		ReleaseJSONLexer(l)
+	}
+
+	l := CreateJSONLexer([]byte(strings.Repeat("[", 5) + strings.Repeat("]", 5)))
+	defer ReleaseJSONLexer(l)
+	l.SetLimits(JSONLimits{MaxDepth: 4})
+	if _, err := l.ReadValue(); !errors.Is(err, ErrJSONTooDeep) {
+		t.Errorf("expected ErrJSONTooDeep, got %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+const _hexDigits = "0123456789abcdef"
+
+var jsonWriterPool = sync.Pool{
+	New: func() any {
+		return &JSONWriter{}
+	},
+}
+
+// CreateJSONWriter return *JSONWriter writing into a pooled BytesBuffer
+func CreateJSONWriter() *JSONWriter {
+	w := jsonWriterPool.Get().(*JSONWriter)
+	w.buf = GetBytesBuffer()
+	w.Reset()
+	return w
+}
+
+// ReleaseJSONWriter puts the writer and its buffer back to the pool
+// Bytes returned by the writer must not be used afterwards
+func ReleaseJSONWriter(w *JSONWriter) {
+	PutBytesBuffer(w.buf)
+	w.buf = nil
+	w.escapeHTML = false
+	jsonWriterPool.Put(w)
+}
+
+// JSONWriter writes a JSON document into a BytesBuffer without reflection
+// Commas and colons are inserted automatically, misuse such as a key outside an object returns an error
+type JSONWriter struct {
+	buf        *BytesBuffer
+	stack      []byte
+	comma      bool // a value was written at the current level
+	afterKey   bool // a key was written and waits for its value
+	complete   bool // the top-level value is complete
+	escapeHTML bool
+	scratch    [64]byte
+}
+
+// Reset clears the written document, the escaping mode is kept
+func (w *JSONWriter) Reset() {
+	w.buf.Reset()
+	w.stack = w.stack[:0]
+	w.comma = false
+	w.afterKey = false
+	w.complete = false
+}
+
+// SetEscapeHTML sets whether <, >, &, U+2028 and U+2029 are escaped in strings
+func (w *JSONWriter) SetEscapeHTML(on bool) {
+	w.escapeHTML = on
+}
+
+// Bytes returns the written document
+// The slice refers to the buffer and is only valid until the next write or release
+func (w *JSONWriter) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+// Complete reports whether a whole top-level value was written
+func (w *JSONWriter) Complete() bool {
+	return w.complete
+}
+
+// beforeValue checks a value may be written and writes the separator
+func (w *JSONWriter) beforeValue() error {
+	if len(w.stack) == 0 {
+		if w.complete {
+			return ErrJSONWriterComplete
+		}
+		return nil
+	}
+	if w.stack[len(w.stack)-1] == _BraceLeft {
+		if !w.afterKey {
+			return ErrJSONWriterValue
+		}
+		w.afterKey = false
+		return nil
+	}
+	if w.comma {
+		w.buf.WriteByte(_CommaChar)
+	}
+	return nil
+}
+
+// afterValue records a complete value at the current level
+func (w *JSONWriter) afterValue() {
+	if len(w.stack) == 0 {
+		w.complete = true
+	} else {
+		w.comma = true
+	}
+}
+
+// BeginObject writes '{'
+func (w *JSONWriter) BeginObject() error {
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	w.buf.WriteByte(_BraceLeft)
+	w.stack = append(w.stack, _BraceLeft)
+	w.comma = false
+	return nil
+}
+
+// Key writes an object key and the following ':'
+func (w *JSONWriter) Key(key string) error {
+	if len(w.stack) == 0 || w.stack[len(w.stack)-1] != _BraceLeft || w.afterKey {
+		return ErrJSONWriterKey
+	}
+	if w.comma {
+		w.buf.WriteByte(_CommaChar)
+	}
+	w.writeQuoted(key)
+	w.buf.WriteByte(_ColonChar)
+	w.afterKey = true
+	return nil
+}
+
+// EndObject writes '}'
+func (w *JSONWriter) EndObject() error {
+	return w.end(_BraceLeft, _BraceRight)
+}
+
+// BeginArray writes '['
+func (w *JSONWriter) BeginArray() error {
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	w.buf.WriteByte(_BracketLeft)
+	w.stack = append(w.stack, _BracketLeft)
+	w.comma = false
+	return nil
+}
+
+// EndArray writes ']'
+func (w *JSONWriter) EndArray() error {
+	return w.end(_BracketLeft, _BracketRight)
+}
+
+// end closes the innermost container if it was opened with begin
+func (w *JSONWriter) end(begin byte, end byte) error {
+	if len(w.stack) == 0 || w.stack[len(w.stack)-1] != begin || w.afterKey {
+		return ErrJSONWriterEnd
+	}
+	w.buf.WriteByte(end)
+	w.stack = w.stack[:len(w.stack)-1]
+	w.afterValue()
+	return nil
+}
+
+// WriteString writes a quoted and escaped JSON string
+// Invalid UTF-8 is replaced with U+FFFD
+func (w *JSONWriter) WriteString(val string) error {
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	w.writeQuoted(val)
+	w.afterValue()
+	return nil
+}
+
+// WriteInt64 writes an integer
+func (w *JSONWriter) WriteInt64(val int64) error {
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	w.buf.Write(strconv.AppendInt(w.scratch[:0], val, 10))
+	w.afterValue()
+	return nil
+}
+
+// WriteUint64 writes an unsigned integer
+func (w *JSONWriter) WriteUint64(val uint64) error {
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	w.buf.Write(strconv.AppendUint(w.scratch[:0], val, 10))
+	w.afterValue()
+	return nil
+}
+
+// WriteFloat64 writes a floating point number in the shortest form that round-trips
+// NaN and infinities are not valid JSON and return an error
+func (w *JSONWriter) WriteFloat64(val float64) error {
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return ErrJSONWriterFloat
+	}
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	w.buf.Write(appendJSONFloat(w.scratch[:0], val, 64))
+	w.afterValue()
+	return nil
+}
+
+// WriteFloat32 writes a float32 in the shortest form that round-trips as float32
+func (w *JSONWriter) WriteFloat32(val float32) error {
+	f := float64(val)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return ErrJSONWriterFloat
+	}
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	w.buf.Write(appendJSONFloat(w.scratch[:0], f, 32))
+	w.afterValue()
+	return nil
+}
+
+// WriteBool writes true or false
+func (w *JSONWriter) WriteBool(val bool) error {
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	if val {
+		w.buf.WriteString("true")
+	} else {
+		w.buf.WriteString("false")
+	}
+	w.afterValue()
+	return nil
+}
+
+// WriteNull writes null
+func (w *JSONWriter) WriteNull() error {
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	w.buf.WriteString("null")
+	w.afterValue()
+	return nil
+}
+
+// WriteRaw writes an already encoded JSON value as is
+// The caller is responsible for raw being a single valid value
+func (w *JSONWriter) WriteRaw(raw []byte) error {
+	if err := w.beforeValue(); err != nil {
+		return err
+	}
+	w.buf.Write(raw)
+	w.afterValue()
+	return nil
+}
+
+// writeQuoted writes s as a quoted JSON string
+func (w *JSONWriter) writeQuoted(s string) {
+	b := w.buf
+	b.WriteByte(_QuoteChar)
+
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != _QuoteChar && c != '\\' && (!w.escapeHTML || (c != '<' && c != '>' && c != '&')) {
+				i++
+				continue
+			}
+			b.WriteString(s[start:i])
+			switch c {
+			case _QuoteChar, '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case '\b':
+				b.WriteString(`\b`)
+			case '\f':
+				b.WriteString(`\f`)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				b.WriteString(`\u00`)
+				b.WriteByte(_hexDigits[c>>4])
+				b.WriteByte(_hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.WriteString(s[start:i])
+			b.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		if w.escapeHTML && (r == '\u2028' || r == '\u2029') {
+			b.WriteString(s[start:i])
+			b.WriteString(`\u202`)
+			b.WriteByte(_hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+
+	b.WriteString(s[start:])
+	b.WriteByte(_QuoteChar)
+}
+
+// appendJSONFloat appends f formatted like encoding/json does
+func appendJSONFloat(dst []byte, f float64, bits int) []byte {
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestJSONWriter(t *testing.T) {
+	w := CreateJSONWriter()
+	defer ReleaseJSONWriter(w)
+
+	steps := []error{
+		w.BeginObject(),
+		w.Key("id"),
+		w.WriteUint64(18446744073709551615),
+		w.Key("name"),
+		w.WriteString("a\"b\\c\n\x01 <tag> \u00e9\u2028"),
+		w.Key("list"),
+		w.BeginArray(),
+		w.WriteInt64(-1),
+		w.WriteFloat64(1.5e-7),
+		w.WriteFloat64(100),
+		w.WriteBool(true),
+		w.WriteNull(),
+		w.BeginObject(),
+		w.EndObject(),
+		w.EndArray(),
+		w.Key("raw"),
+		w.WriteRaw([]byte(`[1,2]`)),
+		w.EndObject(),
+	}
+
+	for i, err := range steps {
+		if err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+	}
+
+	want := `{"id":18446744073709551615,"name":"a\"b\\c\n\u0001 <tag> ` + "\u00e9\u2028" + `","list":[-1,1.5e-7,100,true,null,{}],"raw":[1,2]}`
+	if got := string(w.Bytes()); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if !w.Complete() {
+		t.Errorf("expected complete document")
+	}
+	if !json.Valid(w.Bytes()) {
+		t.Errorf("invalid JSON: %s", w.Bytes())
+	}
+}
+
+func TestJSONWriterEscapeHTML(t *testing.T) {
+	w := CreateJSONWriter()
+	defer ReleaseJSONWriter(w)
+	w.SetEscapeHTML(true)
+
+	if err := w.WriteString("<a>&\u2028\xff"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `"\u003ca\u003e\u0026\u2028\ufffd"`
+	if got := string(w.Bytes()); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestJSONWriterMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(w *JSONWriter) error
+		err  error
+	}{
+		{name: "Key outside object", fn: func(w *JSONWriter) error { return w.Key("a") }, err: ErrJSONWriterKey},
+		{name: "Key in array", fn: func(w *JSONWriter) error { w.BeginArray(); return w.Key("a") }, err: ErrJSONWriterKey},
+		{name: "Key after key", fn: func(w *JSONWriter) error { w.BeginObject(); w.Key("a"); return w.Key("b") }, err: ErrJSONWriterKey},
+		{name: "Value without key", fn: func(w *JSONWriter) error { w.BeginObject(); return w.WriteNull() }, err: ErrJSONWriterValue},
+		{name: "Mismatched end", fn: func(w *JSONWriter) error { w.BeginArray(); return w.EndObject() }, err: ErrJSONWriterEnd},
+		{name: "End without begin", fn: func(w *JSONWriter) error { return w.EndArray() }, err: ErrJSONWriterEnd},
+		{name: "End after key", fn: func(w *JSONWriter) error { w.BeginObject(); w.Key("a"); return w.EndObject() }, err: ErrJSONWriterEnd},
+		{name: "Second top-level value", fn: func(w *JSONWriter) error { w.WriteNull(); return w.WriteNull() }, err: ErrJSONWriterComplete},
+		{name: "NaN", fn: func(w *JSONWriter) error { return w.WriteFloat64(math.NaN()) }, err: ErrJSONWriterFloat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := CreateJSONWriter()
+			defer ReleaseJSONWriter(w)
+
+			if err := tt.fn(w); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v, wrote %s", tt.err, err, w.Bytes())
+			}
+		})
+	}
+}
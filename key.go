@@ -4,6 +4,8 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
 	"encoding/pem"
 )
@@ -93,3 +95,49 @@ func CreatePublicKey(publicKeyPEM []byte) (*ecdsa.PublicKey, error) {
 
 	return ecdsaPublicKey, nil
 }
+
+// SignJSON sign the canonical form of json data, see CanonicalJSON
+// return ASN.1 DER signature
+func SignJSON(privateKey *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+
+	canonical, err := CanonicalJSON(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ecdsa.SignASN1(rand.Reader, privateKey, curveHash(privateKey.Curve, canonical))
+}
+
+// VerifyJSON verify signature of the canonical form of json data
+// return ErrSignatureInvalid if the signature does not match
+func VerifyJSON(publicKey *ecdsa.PublicKey, data []byte, signature []byte) error {
+
+	canonical, err := CanonicalJSON(data)
+
+	if err != nil {
+		return err
+	}
+
+	if !ecdsa.VerifyASN1(publicKey, curveHash(publicKey.Curve, canonical), signature) {
+		return ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+// curveHash hash data with the hash matching the curve size
+func curveHash(curve elliptic.Curve, data []byte) []byte {
+
+	switch bits := curve.Params().BitSize; {
+	case bits > 384:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	case bits > 256:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	default:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}
+}
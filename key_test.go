@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSignJSON(t *testing.T) {
+	privateKeyPEM, err := CreatePrivateKeyPEM()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey, err := CreatePrivateKey(privateKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := SignJSON(privateKey, []byte(`{"b": [1.0, 2e0], "a": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the receiver serializes the same payload differently
+	if err := VerifyJSON(&privateKey.PublicKey, []byte(`{"a":"x","b":[1,2]}`), signature); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+
+	if err := VerifyJSON(&privateKey.PublicKey, []byte(`{"a":"y","b":[1,2]}`), signature); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("expected ErrSignatureInvalid, got %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package utils
 
 import "sync"
 
+//go:generate go run ./cmd/jsongen -type Auth model.go
+
 var (
 	_authPool = sync.Pool{
 		New: func() any {
@@ -0,0 +1,130 @@
+// Code generated by jsongen. DO NOT EDIT.
+
+package utils
+
+import (
+	"bytes"
+)
+
+// MarshalJSON implements json.Marshaler
+func (o Auth) MarshalJSON() ([]byte, error) {
+	w := CreateJSONWriter()
+	defer ReleaseJSONWriter(w)
+	if err := o.EncodeJSON(w); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), w.Bytes()...), nil
+}
+
+// EncodeJSON writes o to w
+func (o *Auth) EncodeJSON(w *JSONWriter) error {
+	if err := w.BeginObject(); err != nil {
+		return err
+	}
+	if err := w.Key("userID"); err != nil {
+		return err
+	}
+	if err := w.WriteUint64(o.UserID); err != nil {
+		return err
+	}
+	if err := w.Key("userRight"); err != nil {
+		return err
+	}
+	if err := w.WriteInt64(o.UserRight); err != nil {
+		return err
+	}
+	return w.EndObject()
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (o *Auth) UnmarshalJSON(data []byte) error {
+	l := CreateJSONLexer(data)
+	defer ReleaseJSONLexer(l)
+	l.SkipWhitespace()
+	if l.Peek() == 'n' {
+		if err := l.ReadNull(); err != nil {
+			return err
+		}
+		return l.ExpectEOF()
+	}
+	if err := o.DecodeJSON(l); err != nil {
+		return err
+	}
+	return l.ExpectEOF()
+}
+
+// DecodeJSON reads o from l, unknown keys are skipped
+// Keys are matched like encoding/json, preferring an exact match but also accepting a case-insensitive one
+func (o *Auth) DecodeJSON(l *JSONLexer) error {
+	if err := l.Expect('{'); err != nil {
+		return err
+	}
+	l.SkipWhitespace()
+	if l.Peek() == '}' {
+		l.Advance()
+		return nil
+	}
+	for {
+		key, err := l.ReadKeyBytes()
+		if err != nil {
+			return err
+		}
+		if err := l.Expect(':'); err != nil {
+			return err
+		}
+		field := 0
+		switch string(key) {
+		case "userID":
+			field = 1
+		case "userRight":
+			field = 2
+		default:
+			switch {
+			case bytes.EqualFold(key, []byte("userID")):
+				field = 1
+			case bytes.EqualFold(key, []byte("userRight")):
+				field = 2
+			}
+		}
+		switch field {
+		case 1:
+			l.SkipWhitespace()
+			if l.Peek() == 'n' {
+				if err := l.ReadNull(); err != nil {
+					return err
+				}
+			} else {
+				v1, err := l.ReadUint64()
+				if err != nil {
+					return err
+				}
+				o.UserID = v1
+			}
+		case 2:
+			l.SkipWhitespace()
+			if l.Peek() == 'n' {
+				if err := l.ReadNull(); err != nil {
+					return err
+				}
+			} else {
+				v2, err := l.ReadInt64()
+				if err != nil {
+					return err
+				}
+				o.UserRight = v2
+			}
+		default:
+			if err := l.SkipValue(); err != nil {
+				return err
+			}
+		}
+		l.SkipWhitespace()
+		if l.Peek() == '}' {
+			l.Advance()
+			return nil
+		}
+		if err := l.Expect(','); err != nil {
+			return err
+		}
+	}
+}
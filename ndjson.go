@@ -0,0 +1,284 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	ndjsonMaxLineSize    = 1 << 20
+	ndjsonInitBufferSize = 4 << 10
+)
+
+// NDJSONError is an error on a line of an NDJSON stream
+type NDJSONError struct {
+	Line int
+	Err  error
+}
+
+func (e *NDJSONError) Error() string {
+	return fmt.Sprintf("ndjson line %d: %v", e.Line, e.Err)
+}
+
+func (e *NDJSONError) Unwrap() error {
+	return e.Err
+}
+
+// NDJSONReader reads newline delimited JSON records, one per line
+// Memory is bounded by the maximum line size, the buffer starts small and grows up to it
+type NDJSONReader struct {
+	r    io.Reader
+	buf  []byte
+	pos  int // start of the unread bytes in buf
+	n    int // end of the unread bytes in buf
+	max  int
+	err  error // read error returned once the buffer is drained
+	line int
+}
+
+// NewNDJSONReader return *NDJSONReader reading from r
+// Lines longer than maxLineSize are reported with ErrJSONLineTooLong, 0 means 1 MiB
+func NewNDJSONReader(r io.Reader, maxLineSize int) *NDJSONReader {
+
+	if maxLineSize <= 0 {
+		maxLineSize = ndjsonMaxLineSize
+	}
+
+	size := ndjsonInitBufferSize
+
+	if size > maxLineSize {
+		size = maxLineSize
+	}
+
+	return &NDJSONReader{r: r, buf: make([]byte, size), max: maxLineSize}
+}
+
+// Line returns the line number of the last record read
+func (r *NDJSONReader) Line() int {
+	return r.line
+}
+
+// Next returns the next record, blank lines are skipped
+// It returns io.EOF after the last record
+// The record is checked with SkipValue to be a single JSON value, the slice is only valid until the next call
+// After a *NDJSONError, including a line that is too long, reading continues with the next line
+func (r *NDJSONReader) Next() ([]byte, error) {
+
+	for {
+		line, err := r.readLine()
+
+		if err == ErrJSONLineTooLong {
+			r.line++
+			return nil, &NDJSONError{Line: r.line, Err: err}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		r.line++
+
+		record, err := scanJSONRecord(line)
+
+		if err != nil {
+			return nil, &NDJSONError{Line: r.line, Err: err}
+		}
+
+		if record != nil {
+			return record, nil
+		}
+	}
+}
+
+// readLine returns the next line with its newline, the last line may have none
+// A line that does not fit in the maximum size is dropped and reported with ErrJSONLineTooLong
+func (r *NDJSONReader) readLine() ([]byte, error) {
+
+	scanned := 0 // unread bytes known to hold no newline
+	tooLong := false
+
+	for {
+		if i := bytes.IndexByte(r.buf[r.pos+scanned:r.n], '\n'); i >= 0 {
+			end := r.pos + scanned + i + 1
+			line := r.buf[r.pos:end]
+			r.pos = end
+			if tooLong {
+				return nil, ErrJSONLineTooLong
+			}
+			return line, nil
+		}
+
+		scanned = r.n - r.pos
+
+		if r.err != nil {
+			line := r.buf[r.pos:r.n]
+			r.pos = r.n
+			if tooLong {
+				return nil, ErrJSONLineTooLong
+			}
+			if len(line) == 0 {
+				return nil, r.err
+			}
+			return line, nil
+		}
+
+		if r.pos > 0 {
+			r.n = copy(r.buf, r.buf[r.pos:r.n])
+			r.pos = 0
+		}
+
+		if r.n == len(r.buf) {
+			if len(r.buf) >= r.max {
+				// drop the start of the line and look for its end
+				tooLong = true
+				r.n, scanned = 0, 0
+			} else {
+				size := 2 * len(r.buf)
+				if size > r.max {
+					size = r.max
+				}
+				buf := make([]byte, size)
+				copy(buf, r.buf[:r.n])
+				r.buf = buf
+			}
+		}
+
+		m, err := r.r.Read(r.buf[r.n:])
+		r.n += m
+
+		if err != nil {
+			r.err = err
+		}
+	}
+}
+
+// Decode reads the next record and passes it to fn through a pooled JSONLexer
+func (r *NDJSONReader) Decode(fn func(l *JSONLexer) error) error {
+
+	record, err := r.Next()
+
+	if err != nil {
+		return err
+	}
+
+	l := CreateJSONLexer(record)
+	defer ReleaseJSONLexer(l)
+
+	if err := fn(l); err != nil {
+		return &NDJSONError{Line: r.line, Err: err}
+	}
+
+	return nil
+}
+
+// scanJSONRecord checks line holds exactly one JSON value and returns it without the surrounding whitespace
+// A blank line returns nil, error offsets count from the start of the line
+func scanJSONRecord(line []byte) ([]byte, error) {
+
+	// the whole line is in the buffer, so the streaming functions only read the end of the input
+	var end eofReader
+	var limits JSONLimits
+
+	base, tokens := 0, 0
+
+	start, n, err := readUntilNonWhitespace(end, line, 0, len(line), &base)
+
+	if err == io.EOF {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	pos, n, err := skipValue(end, line, start, n, &base, &limits, &tokens)
+
+	// a value cut short by the end of the line must not read as the end of the stream
+	if errors.Is(err, io.EOF) {
+		return nil, &JSONSyntaxError{Offset: len(line), Msg: "unexpected end of line"}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// reading the end of the input empties buf without moving its content, so base+pos is still in line
+	record := line[start : base+pos]
+
+	if pos, _, err = readUntilNonWhitespace(end, line, pos, n, &base); err != io.EOF {
+		return nil, &JSONSyntaxError{Offset: base + pos, Msg: "unexpected data after value"}
+	}
+
+	return record, nil
+}
+
+// eofReader is an empty reader
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+// NDJSONWriter writes newline delimited JSON records
+type NDJSONWriter struct {
+	w io.Writer
+}
+
+// NewNDJSONWriter return *NDJSONWriter writing to w
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: w}
+}
+
+// WriteRaw writes raw as one record, raw must be a single JSON value without newlines
+func (w *NDJSONWriter) WriteRaw(raw []byte) error {
+
+	if bytes.IndexByte(raw, '\n') >= 0 {
+		return ErrNDJSONNewline
+	}
+
+	buf := GetBytesBuffer()
+	defer PutBytesBuffer(buf)
+
+	buf.Write(raw)
+	buf.WriteByte('\n')
+
+	_, err := w.w.Write(buf.Bytes())
+
+	return err
+}
+
+// Write builds one record with a pooled JSONWriter
+func (w *NDJSONWriter) Write(fn func(jw *JSONWriter) error) error {
+
+	jw := CreateJSONWriter()
+	defer ReleaseJSONWriter(jw)
+
+	if err := fn(jw); err != nil {
+		return err
+	}
+
+	if !jw.Complete() {
+		return ErrJSONWriterEnd
+	}
+
+	jw.buf.WriteByte('\n')
+
+	_, err := w.w.Write(jw.Bytes())
+
+	return err
+}
+
+// Encode writes v as one record using encoding/json
+func (w *NDJSONWriter) Encode(v any) error {
+
+	raw, err := json.Marshal(v)
+
+	if err != nil {
+		return err
+	}
+
+	return w.WriteRaw(raw)
+}
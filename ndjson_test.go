@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNDJSONReader(t *testing.T) {
+	input := "{\"a\":1}\r\n\n  [1, 2]  \n{\"long\": \"" + strings.Repeat("x", 64) + "\"}\n{\"a\":\n\"tail\" 1\n\"last\""
+
+	r := NewNDJSONReader(strings.NewReader(input), 32)
+
+	steps := []struct {
+		want    string
+		line    int
+		wantErr error
+	}{
+		{want: `{"a":1}`, line: 1},
+		{want: `[1, 2]`, line: 3},
+		{wantErr: ErrJSONLineTooLong, line: 4},
+		{wantErr: errors.New("syntax"), line: 5},
+		{wantErr: errors.New("syntax"), line: 6},
+		{want: `"last"`, line: 7},
+		{wantErr: io.EOF, line: 7},
+	}
+
+	for i, step := range steps {
+		got, err := r.Next()
+
+		if step.wantErr != nil {
+			var lineErr *NDJSONError
+			switch {
+			case step.wantErr == io.EOF:
+				if err != io.EOF {
+					t.Fatalf("step %d: expected io.EOF, got %v", i, err)
+				}
+			case !errors.As(err, &lineErr) || lineErr.Line != step.line:
+				t.Fatalf("step %d: expected error on line %d, got %v", i, step.line, err)
+			case step.wantErr == ErrJSONLineTooLong && !errors.Is(err, ErrJSONLineTooLong):
+				t.Fatalf("step %d: expected ErrJSONLineTooLong, got %v", i, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		if string(got) != step.want || r.Line() != step.line {
+			t.Fatalf("step %d: expected %s on line %d, got %s on line %d", i, step.want, step.line, got, r.Line())
+		}
+	}
+}
+
+func TestNDJSONReaderRecords(t *testing.T) {
+	long := `"` + strings.Repeat("x", 3*ndjsonInitBufferSize) + `"`
+	input := long + "\n[1 2]\n{\"a\" 1}\n{\"a\": [1,]}\n{\"a\": {\"b\":\n \t \n{\"a\": {\"b\": [null, true]}}\n-1.5e3"
+
+	r := NewNDJSONReader(strings.NewReader(input), 0)
+
+	if len(r.buf) != ndjsonInitBufferSize {
+		t.Errorf("expected an initial buffer of %d bytes, got %d", ndjsonInitBufferSize, len(r.buf))
+	}
+
+	got, err := r.Next()
+	if err != nil || string(got) != long {
+		t.Fatalf("expected the long record, got %d bytes %v", len(got), err)
+	}
+
+	if len(r.buf) > 4*ndjsonInitBufferSize {
+		t.Errorf("expected the buffer to grow to fit the line, got %d bytes", len(r.buf))
+	}
+
+	// malformed records are reported on their line, not as the end of the stream
+	for line := 2; line <= 5; line++ {
+		_, err := r.Next()
+		var lineErr *NDJSONError
+		if !errors.As(err, &lineErr) || lineErr.Line != line || errors.Is(err, io.EOF) {
+			t.Fatalf("expected error on line %d, got %v", line, err)
+		}
+	}
+
+	got, err = r.Next()
+	if err != nil || string(got) != `{"a": {"b": [null, true]}}` || r.Line() != 7 {
+		t.Fatalf("expected last record on line 7, got %s on line %d %v", got, r.Line(), err)
+	}
+
+	// a number ends at the end of the input
+	if got, err = r.Next(); err != nil || string(got) != "-1.5e3" {
+		t.Fatalf("expected -1.5e3, got %s %v", got, err)
+	}
+
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewNDJSONWriter(&out)
+
+	if err := w.WriteRaw([]byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(func(jw *JSONWriter) error { return jw.WriteString("x") }); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Encode([]int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRaw([]byte("[1,\n2]")); !errors.Is(err, ErrNDJSONNewline) {
+		t.Error("expected error for record with newline")
+	}
+	if err := w.Write(func(jw *JSONWriter) error { return jw.BeginArray() }); err == nil {
+		t.Error("expected error for incomplete record")
+	}
+
+	want := "{\"a\":1}\n\"x\"\n[1,2]\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
@@ -123,6 +123,39 @@ func SqlOrderBy(orderBy string, w io.Writer, prefix string, fn func(variableName
 	return nil
 }
 
+// SqlUpdateVersion create sql for optimistic update and args
+// the statement sets keys to vals, increments `version` and matches on `id` and `version`
+// use CheckVersion on the result to detect a concurrent modification
+func SqlUpdateVersion(table string, w io.Writer, args *[]any, keys []string, vals []any, id any, version uint64) error {
+
+	l := len(keys)
+
+	if l == 0 || l != len(vals) {
+		return ErrLengthInvalid
+	}
+
+	for _, key := range keys {
+		if key == "id" || key == "version" {
+			return ErrInvalid
+		}
+	}
+
+	fmt.Fprintf(w, "UPDATE `%s` SET ", table)
+
+	for i := 0; i < l; i++ {
+
+		fmt.Fprintf(w, "`%s` = ?, ", keys[i])
+
+		*args = append(*args, vals[i])
+	}
+
+	w.Write([]byte("`version` = `version` + 1 WHERE `id` = ? AND `version` = ?"))
+
+	*args = append(*args, id, version)
+
+	return nil
+}
+
 // filterParse parse filter to vals
 func filterParse(filter string) []string {
 	l := len(filter)
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSqlUpdateVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		vals []any
+		sql  string
+		args []any
+		err  error
+	}{
+		{
+			name: "update",
+			keys: []string{"name", "email"},
+			vals: []any{"bob", "bob@example.com"},
+			sql:  "UPDATE `user` SET `name` = ?, `email` = ?, `version` = `version` + 1 WHERE `id` = ? AND `version` = ?",
+			args: []any{"bob", "bob@example.com", 7, uint64(3)},
+		},
+		{
+			name: "empty",
+			err:  ErrLengthInvalid,
+		},
+		{
+			name: "length mismatch",
+			keys: []string{"name"},
+			vals: []any{"bob", "x"},
+			err:  ErrLengthInvalid,
+		},
+		{
+			name: "id",
+			keys: []string{"name", "id"},
+			vals: []any{"bob", 8},
+			err:  ErrInvalid,
+		},
+		{
+			name: "version",
+			keys: []string{"name", "version"},
+			vals: []any{"bob", uint64(4)},
+			err:  ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w strings.Builder
+			var args []any
+
+			err := SqlUpdateVersion("user", &w, &args, tt.keys, tt.vals, 7, 3)
+
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			// nothing is written on error
+			if w.String() != tt.sql {
+				t.Errorf("expected %q, got %q", tt.sql, w.String())
+			}
+
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("expected %v, got %v", tt.args, args)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// CheckVersion check result of a versioned update
+// return ErrVersionInvalid if no row was affected
+func CheckVersion(result sql.Result) error {
+
+	n, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrVersionInvalid
+	}
+
+	return nil
+}
+
+// VersionETag return strong etag for version
+func VersionETag(version uint64) string {
+	return "\"" + strconv.FormatUint(version, 10) + "\""
+}
+
+// ParseETag parse version from etag, weak etag is accepted
+func ParseETag(etag string) (uint64, error) {
+
+	etag = strings.TrimSpace(etag)
+	etag = strings.TrimPrefix(etag, "W/")
+
+	l := len(etag)
+
+	if l < 3 || etag[0] != '"' || etag[l-1] != '"' {
+		return 0, ErrVersionInvalid
+	}
+
+	version, err := strconv.ParseUint(etag[1:l-1], 10, 64)
+
+	if err != nil {
+		return 0, ErrVersionInvalid
+	}
+
+	return version, nil
+}
+
+// SetETag sets the ETag header for version
+func SetETag(set func(key string, value string), version uint64) {
+	set("ETag", VersionETag(version))
+}
+
+// IfMatch return version from If-Match header
+// return ErrVersionInvalid if the header is missing, "*" or lists more than one etag
+func IfMatch(get func(key string) string) (uint64, error) {
+
+	val := get("If-Match")
+
+	if val == "" || strings.Contains(val, ",") {
+		return 0, ErrVersionInvalid
+	}
+
+	return ParseETag(val)
+}
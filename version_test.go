@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+// testResult is a sql.Result with a fixed number of affected rows
+type testResult struct {
+	rows int64
+	err  error
+}
+
+func (r testResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (r testResult) RowsAffected() (int64, error) {
+	return r.rows, r.err
+}
+
+func TestCheckVersion(t *testing.T) {
+	errRows := errors.New("rows unsupported")
+
+	tests := []struct {
+		result testResult
+		err    error
+	}{
+		{testResult{rows: 1}, nil},
+		{testResult{rows: 0}, ErrVersionInvalid},
+		{testResult{err: errRows}, errRows},
+	}
+
+	for _, tt := range tests {
+		if err := CheckVersion(tt.result); !errors.Is(err, tt.err) {
+			t.Errorf("%+v: expected %v, got %v", tt.result, tt.err, err)
+		}
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	header := map[string]string{}
+
+	SetETag(func(key, value string) { header[key] = value }, 42)
+
+	if got := header["ETag"]; got != `"42"` {
+		t.Errorf(`expected "42", got %s`, got)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version uint64
+		err     error
+	}{
+		{`"42"`, 42, nil},
+		{` W/"7" `, 7, nil},
+		{``, 0, ErrVersionInvalid},
+		{`*`, 0, ErrVersionInvalid},
+		{`"1", "2"`, 0, ErrVersionInvalid},
+		{`42`, 0, ErrVersionInvalid},
+		{`"x"`, 0, ErrVersionInvalid},
+		{`""`, 0, ErrVersionInvalid},
+	}
+
+	for _, tt := range tests {
+		version, err := IfMatch(func(key string) string {
+			if key != "If-Match" {
+				t.Errorf("unexpected header %s", key)
+			}
+			return tt.header
+		})
+
+		if version != tt.version || !errors.Is(err, tt.err) {
+			t.Errorf("%q: expected %d %v, got %d %v", tt.header, tt.version, tt.err, version, err)
+		}
+	}
+}